@@ -1,11 +1,14 @@
 package kuanzhan
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
 	"sort"
 	"strings"
+
+	"golang.org/x/time/rate"
 )
 
 type Client struct {
@@ -13,6 +16,7 @@ type Client struct {
 	AppKey    string
 	AppSecret string
 	debug     bool
+	limiter   *rate.Limiter
 	impls     *impls
 }
 
@@ -92,6 +96,26 @@ func (c *Client) SetDebug(debug bool) {
 	c.debug = debug
 }
 
+// SetRateLimit 设置每秒请求数限制，r <= 0 时不限制
+func (c *Client) SetRateLimit(r float64, burst int) {
+	if r <= 0 {
+		c.limiter = nil
+		return
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	c.limiter = rate.NewLimiter(rate.Limit(r), burst)
+}
+
+// wait 等待请求配额
+func (c *Client) wait() error {
+	if c.limiter == nil {
+		return nil
+	}
+	return c.limiter.Wait(context.Background())
+}
+
 // SignMethod 生成API请求签名
 // params: 包含所有请求参数的map（不包含sign参数）
 // 返回: MD5签名字符串（32位十六进制）
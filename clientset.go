@@ -0,0 +1,215 @@
+package kuanzhan
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrSiteNotFound 所有账号中都没有找到站点
+var ErrSiteNotFound = errors.New("kuanzhan: site not found in any account")
+
+// Account ClientSet 中的一个账号
+type Account struct {
+	Name   string
+	Client *Client
+}
+
+// AccountError 单个账号的操作错误
+type AccountError struct {
+	Account string
+	Err     error
+}
+
+func (e *AccountError) Error() string {
+	return e.Account + ": " + e.Err.Error()
+}
+
+func (e *AccountError) Unwrap() error {
+	return e.Err
+}
+
+// AccountSite 账号下的站点
+type AccountSite struct {
+	Account string
+	SiteId  int
+}
+
+// ClientSet 多账号客户端集合，用于跨账号的并发操作
+// 每个账号使用独立的 Client，速率限制按账号生效。
+type ClientSet struct {
+	accounts []*Account
+}
+
+// NewClientSet creates a new client set
+func NewClientSet(accounts ...*Account) *ClientSet {
+	return &ClientSet{accounts: accounts}
+}
+
+// NewClientSetFromFile 使用配置文件中的 profile 创建 ClientSet，names 为空时使用所有 profile
+// 每个账号按其 profile 的 rate_limit 限速，profile 没有配置时使用顶级的 rate_limit
+func NewClientSetFromFile(path string, names []string, passphrase PassphraseFunc) (*ClientSet, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fc fileConfig
+	if err := yaml.Unmarshal(b, &fc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	if len(names) == 0 {
+		names = fc.profileNames()
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no profiles found in %s", path)
+	}
+
+	set := &ClientSet{}
+	for _, name := range names {
+		client, err := NewClientWithProvider(&FileProvider{Path: path, Profile: name, Passphrase: passphrase})
+		if err != nil {
+			return nil, &AccountError{Account: name, Err: err}
+		}
+		client.SetRateLimit(cmp.Or(fc.Profiles[name].RateLimit, fc.RateLimit), 1)
+		set.accounts = append(set.accounts, &Account{Name: name, Client: client})
+	}
+	return set, nil
+}
+
+// ProfileNames 配置文件中的 profile 名称，按名称排序
+// 顶级配置了凭证且没有 profiles.default 时包含 default
+func ProfileNames(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fc fileConfig
+	if err := yaml.Unmarshal(b, &fc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return fc.profileNames(), nil
+}
+
+func (fc *fileConfig) profileNames() []string {
+	names := make([]string, 0, len(fc.Profiles)+1)
+	for name := range fc.Profiles {
+		names = append(names, name)
+	}
+	if _, ok := fc.Profiles["default"]; !ok && (fc.AppKey != "" || fc.CredentialProcess != "") {
+		names = append(names, "default")
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Accounts 所有账号
+func (s *ClientSet) Accounts() []*Account {
+	return s.accounts
+}
+
+// Get 按名称获取账号的客户端，不存在时返回 nil
+func (s *ClientSet) Get(name string) *Client {
+	if a := s.account(name); a != nil {
+		return a.Client
+	}
+	return nil
+}
+
+// SetDebug 设置所有账号的调试模式
+func (s *ClientSet) SetDebug(debug bool) {
+	for _, a := range s.accounts {
+		a.Client.SetDebug(debug)
+	}
+}
+
+// SetRateLimit 设置每个账号的每秒请求数限制
+func (s *ClientSet) SetRateLimit(r float64, burst int) {
+	for _, a := range s.accounts {
+		a.Client.SetRateLimit(r, burst)
+	}
+}
+
+// ForEach 并发对每个账号执行 fn，返回所有账号错误的合并（*AccountError）
+func (s *ClientSet) ForEach(ctx context.Context, fn func(ctx context.Context, a *Account) error) error {
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(s.accounts))
+	)
+	for i, a := range s.accounts {
+		wg.Add(1)
+		go func(i int, a *Account) {
+			defer wg.Done()
+			if err := ctx.Err(); err != nil {
+				errs[i] = &AccountError{Account: a.Name, Err: err}
+				return
+			}
+			if err := fn(ctx, a); err != nil {
+				errs[i] = &AccountError{Account: a.Name, Err: err}
+			}
+		}(i, a)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// ListSites 列出所有账号的站点，按账号和站点ID排序
+// 部分账号失败时仍返回其他账号的站点
+func (s *ClientSet) ListSites(ctx context.Context) ([]AccountSite, error) {
+	var (
+		mu    sync.Mutex
+		sites []AccountSite
+	)
+	err := s.ForEach(ctx, func(ctx context.Context, a *Account) error {
+		resp, err := a.Client.GetSiteIds()
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, siteId := range resp.Data.SiteIds {
+			sites = append(sites, AccountSite{Account: a.Name, SiteId: siteId})
+		}
+		return nil
+	})
+
+	slices.SortFunc(sites, func(a, b AccountSite) int {
+		if c := strings.Compare(a.Account, b.Account); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.SiteId, b.SiteId)
+	})
+	return sites, err
+}
+
+// FindSite 查找站点所属的账号
+func (s *ClientSet) FindSite(ctx context.Context, siteId int) (*Account, error) {
+	sites, err := s.ListSites(ctx)
+	for _, site := range sites {
+		if site.SiteId == siteId {
+			return s.account(site.Account), nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, ErrSiteNotFound
+}
+
+func (s *ClientSet) account(name string) *Account {
+	for _, a := range s.accounts {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package kuanzhan
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+// newTestClientSet 每个账号的 appKey 对应一组站点，appKey 为 bad 时返回错误
+func newTestClientSet(t *testing.T, sites map[string]string) *ClientSet {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		data, ok := sites[r.Form.Get("appKey")]
+		if !ok {
+			w.Write([]byte(`{"code":401,"msg":"invalid appKey"}`))
+			return
+		}
+		w.Write([]byte(`{"code":200,"msg":"ok","data":{"siteIds":` + data + `}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	var accounts []*Account
+	for _, name := range []string{"a", "b", "bad"} {
+		client := NewClient(name, "secret")
+		client.BaseURL = srv.URL
+		accounts = append(accounts, &Account{Name: name, Client: client})
+	}
+	set := NewClientSet(accounts...)
+	set.SetRateLimit(100, 1)
+	return set
+}
+
+func TestClientSet_ListSites(t *testing.T) {
+	set := newTestClientSet(t, map[string]string{"a": "[3,1]", "b": "[2]"})
+
+	sites, err := set.ListSites(context.Background())
+	want := []AccountSite{{"a", 1}, {"a", 3}, {"b", 2}}
+	if len(sites) != len(want) {
+		t.Fatalf("got %v, want %v", sites, want)
+	}
+	for i := range want {
+		if sites[i] != want[i] {
+			t.Errorf("sites[%d] = %v, want %v", i, sites[i], want[i])
+		}
+	}
+
+	var accountErr *AccountError
+	if !errors.As(err, &accountErr) || accountErr.Account != "bad" {
+		t.Errorf("expected error for account bad, got %v", err)
+	}
+}
+
+func TestClientSet_FindSite(t *testing.T) {
+	set := newTestClientSet(t, map[string]string{"a": "[1]", "b": "[2]"})
+
+	account, err := set.FindSite(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Name != "b" {
+		t.Errorf("got account %s", account.Name)
+	}
+
+	if _, err := set.FindSite(context.Background(), 9); err == nil {
+		t.Error("expected error for unknown site")
+	}
+}
+
+func TestProfileNames(t *testing.T) {
+	names, err := ProfileNames(writeTestConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"default", "process", "production"}
+	if len(names) != len(want) {
+		t.Fatalf("got %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("got %v, want %v", names, want)
+		}
+	}
+}
+
+func TestNewClientSetFromFile_RateLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kuanzhan.yaml")
+	config := `rate_limit: 1
+profiles:
+  a:
+    app_key: a_key
+    app_secret: a_secret
+    rate_limit: 2
+  b:
+    app_key: b_key
+    app_secret: b_secret
+    rate_limit: 5
+  c:
+    app_key: c_key
+    app_secret: c_secret
+`
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	set, err := NewClientSetFromFile(path, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]rate.Limit{"a": 2, "b": 5, "c": 1}
+	for name, limit := range want {
+		client := set.Get(name)
+		if client == nil || client.limiter == nil || client.limiter.Limit() != limit {
+			t.Errorf("account %s: got %+v, want limit %v", name, client, limit)
+		}
+	}
+
+	set.SetRateLimit(0, 1)
+	if set.Get("a").limiter != nil {
+		t.Error("SetRateLimit should override the profile rate_limit")
+	}
+}
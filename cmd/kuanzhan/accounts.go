@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"pkg.blksails.net/kuanzhan"
+)
+
+var rateLimit float64 // 每个账号每秒请求数
+
+var findSiteCmd = &cobra.Command{
+	Use:   "find-site <siteId>",
+	Short: "查找站点所属账号",
+	Long:  "在配置文件的所有 profile（或 --profile 指定的多个 profile）中查找站点所属的账号",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		siteId, err := strconv.Atoi(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var names []string
+		if multiProfile() {
+			names = profileNamesArg()
+		}
+		if useCached {
+			account, err := findCachedSite(names, siteId)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println(account)
+			return
+		}
+
+		set, err := kuanzhan.NewClientSetFromFile(viper.ConfigFileUsed(), names, passphrase)
+		if err != nil {
+			log.Fatal(err)
+		}
+		set.SetDebug(debug)
+		if rateLimitChanged() {
+			set.SetRateLimit(rateLimit, 1)
+		}
+
+		account, err := set.FindSite(context.Background(), siteId)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(account.Name)
+	},
+}
+
+func init() {
+	rootCmd.PersistentFlags().Float64Var(&rateLimit, "rate-limit", 0, "每个账号每秒最多请求数，0 表示不限制")
+	rootCmd.AddCommand(findSiteCmd)
+}
+
+// multiProfile 是否通过 --profile all 或逗号分隔的列表指定了多个账号
+func multiProfile() bool {
+	return profile == "all" || strings.Contains(profile, ",")
+}
+
+// profileNamesArg --profile 指定的 profile 列表，all 时返回 nil 表示所有 profile
+func profileNamesArg() []string {
+	if profile == "all" {
+		return nil
+	}
+	var names []string
+	for _, name := range strings.Split(profile, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// newClientSet 只读命令使用的多账号客户端
+// 单个 profile 时使用完整的凭证链，多个 profile 时从配置文件读取每个账号的凭证
+func newClientSet() *kuanzhan.ClientSet {
+	if !multiProfile() {
+		return kuanzhan.NewClientSet(&kuanzhan.Account{Name: profile, Client: newClient()})
+	}
+
+	set, err := kuanzhan.NewClientSetFromFile(viper.ConfigFileUsed(), profileNamesArg(), passphrase)
+	if err != nil {
+		log.Fatal(err)
+	}
+	set.SetDebug(debug)
+	if rateLimitChanged() {
+		set.SetRateLimit(rateLimit, 1)
+	}
+	return set
+}
+
+// rateLimitChanged 是否在命令行显式指定了 --rate-limit
+// 多个 profile 时每个账号默认使用其 profile 的 rate_limit，显式指定时覆盖所有账号
+func rateLimitChanged() bool {
+	return rootCmd.PersistentFlags().Changed("rate-limit")
+}
+
+// findCachedSite 在本地清单中查找站点所属的账号，names 为空时查找所有 profile
+func findCachedSite(names []string, siteId int) (string, error) {
+	if len(names) == 0 {
+		var err error
+		if names, err = kuanzhan.ProfileNames(viper.ConfigFileUsed()); err != nil {
+			return "", err
+		}
+	}
+
+	inv, err := openInventory(inventoryPath())
+	if err != nil {
+		return "", err
+	}
+	defer inv.Close()
+
+	for _, name := range names {
+		if _, err := inv.Site(name, siteId); err == nil {
+			return name, nil
+		} else if !errors.Is(err, errNotInInventory) {
+			return "", err
+		}
+	}
+	return "", kuanzhan.ErrSiteNotFound
+}
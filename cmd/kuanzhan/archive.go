@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"pkg.blksails.net/kuanzhan"
+)
+
+// uploadsBucket uploads/<account>/<pageId><unix nano> -> uploadRecord
+var uploadsBucket = []byte("uploads")
+
+// uploadRecord 一次上传到页面的内容，内容按 sha256 存储在内容归档中
+type uploadRecord struct {
+	Account    string    `json:"account"`
+	SiteID     int       `json:"siteId"`
+	PageID     int       `json:"pageId"`
+	Hash       string    `json:"hash"`
+	Source     string    `json:"source"`
+	TaskID     string    `json:"taskId,omitempty"`
+	UploadedAt time.Time `json:"uploadedAt"`
+}
+
+// contentDir 内容归档目录，与本地清单放在同一目录
+func contentDir() string {
+	return filepath.Join(filepath.Dir(inventoryPath()), "content")
+}
+
+// contentHash 内容的 sha256，作为归档中的文件名
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// putContent 将内容写入归档，返回 sha256
+func putContent(content []byte) (string, error) {
+	hash := contentHash(content)
+	path := filepath.Join(contentDir(), hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+
+	if err := os.MkdirAll(contentDir(), 0700); err != nil {
+		return "", err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return "", err
+	}
+	return hash, os.Rename(tmp, path)
+}
+
+// readContent 读取归档中的内容
+func readContent(hash string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(contentDir(), hash))
+}
+
+func uploadKey(pageId int, t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(siteKey(pageId), uint64(t.UnixNano()))
+}
+
+// PutUploads 记录上传
+func (inv *inventory) PutUploads(records ...*uploadRecord) error {
+	return inv.db.Update(func(tx *bolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists(uploadsBucket)
+		if err != nil {
+			return err
+		}
+		for _, rec := range records {
+			b, err := root.CreateBucketIfNotExists([]byte(rec.Account))
+			if err != nil {
+				return err
+			}
+			v, err := json.Marshal(rec)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(uploadKey(rec.PageID, rec.UploadedAt), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Uploads 页面的上传记录，按上传时间排序
+func (inv *inventory) Uploads(account string, pageId int) ([]*uploadRecord, error) {
+	var records []*uploadRecord
+	err := inv.db.View(func(tx *bolt.Tx) error {
+		b := uploadsAccountBucket(tx, account)
+		if b == nil {
+			return nil
+		}
+		prefix := siteKey(pageId)
+		c := b.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			rec := &uploadRecord{}
+			if err := json.Unmarshal(v, rec); err != nil {
+				return err
+			}
+			records = append(records, rec)
+		}
+		return nil
+	})
+	return records, err
+}
+
+// LatestUploads 账号下每个页面最近一次的上传记录，按页面ID排序
+func (inv *inventory) LatestUploads(account string) ([]*uploadRecord, error) {
+	var records []*uploadRecord
+	err := inv.db.View(func(tx *bolt.Tx) error {
+		b := uploadsAccountBucket(tx, account)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			rec := &uploadRecord{}
+			if err := json.Unmarshal(v, rec); err != nil {
+				return err
+			}
+			if n := len(records); n > 0 && records[n-1].PageID == rec.PageID {
+				records[n-1] = rec
+			} else {
+				records = append(records, rec)
+			}
+			return nil
+		})
+	})
+	return records, err
+}
+
+func uploadsAccountBucket(tx *bolt.Tx, account string) *bolt.Bucket {
+	root := tx.Bucket(uploadsBucket)
+	if root == nil {
+		return nil
+	}
+	return root.Bucket([]byte(account))
+}
+
+// resolvePageSites 确定页面所属的站点
+// 只有一个站点时所有页面属于该站点，否则依次查找 known、本地清单和 GetPageName
+func resolvePageSites(client *kuanzhan.Client, inv *inventory, siteIds, pageIds []int, known map[int]int) map[int]int {
+	pageSites := make(map[int]int, len(pageIds))
+	var missing []int
+	for _, pageId := range pageIds {
+		switch {
+		case known[pageId] != 0:
+			pageSites[pageId] = known[pageId]
+		case len(siteIds) == 1:
+			pageSites[pageId] = siteIds[0]
+		default:
+			missing = append(missing, pageId)
+		}
+	}
+	if len(missing) == 0 {
+		return pageSites
+	}
+
+	for _, siteId := range siteIds {
+		if rec, err := inv.Site(profile, siteId); err == nil && rec.Pages != nil {
+			for _, page := range rec.Pages {
+				pageSites[page.PageID] = siteId
+			}
+			continue
+		}
+		resp, err := client.GetPageName(siteId)
+		if err != nil {
+			log.Printf("GetPageName %d: %v", siteId, err)
+			continue
+		}
+		for _, page := range resp.Data {
+			pageSites[page.PageId] = siteId
+		}
+	}
+	return pageSites
+}
+
+// archiveUpload 将上传的内容写入归档并记录每个页面的上传
+func archiveUpload(client *kuanzhan.Client, content []byte, source, taskId string, siteIds, pageIds []int, known map[int]int) error {
+	hash, err := putContent(content)
+	if err != nil {
+		return err
+	}
+
+	inv, err := openInventory(inventoryPath())
+	if err != nil {
+		return err
+	}
+	defer inv.Close()
+
+	pageSites := resolvePageSites(client, inv, siteIds, pageIds, known)
+	now := time.Now()
+	records := make([]*uploadRecord, 0, len(pageIds))
+	for _, pageId := range pageIds {
+		records = append(records, &uploadRecord{
+			Account:    profile,
+			SiteID:     pageSites[pageId],
+			PageID:     pageId,
+			Hash:       hash,
+			Source:     source,
+			TaskID:     taskId,
+			UploadedAt: now,
+		})
+	}
+	if err := inv.PutUploads(records...); err != nil {
+		return fmt.Errorf("record uploads: %w", err)
+	}
+	return nil
+}
+
+// printUploadDiff 输出每个页面最近一次上传的内容与新内容的差异
+func printUploadDiff(w io.Writer, pageIds []int, contents map[int][]byte, hashes map[int]string) error {
+	for _, pageId := range pageIds {
+		content := contents[pageId]
+		hash := contentHash(content)
+		switch previous := hashes[pageId]; previous {
+		case "":
+			fmt.Fprintf(w, "page %d: no archived content, the whole page will be replaced\n", pageId)
+		case hash:
+			fmt.Fprintf(w, "page %d: unchanged\n", pageId)
+		default:
+			old, err := readContent(previous)
+			if err != nil {
+				return fmt.Errorf("page %d: %w", pageId, err)
+			}
+			oldName := fmt.Sprintf("page %d (%s)", pageId, shortHash(previous))
+			newName := fmt.Sprintf("page %d (%s)", pageId, shortHash(hash))
+			fmt.Fprint(w, unifiedDiff(oldName, newName, old, content, 3))
+		}
+	}
+	return nil
+}
+
+// changedPages 最近一次上传的内容与新内容不同（或没有上传记录）的页面
+func changedPages(pageIds []int, contents map[int][]byte, hashes map[int]string) []int {
+	var changed []int
+	for _, pageId := range pageIds {
+		if hashes[pageId] != contentHash(contents[pageId]) {
+			changed = append(changed, pageId)
+		}
+	}
+	return changed
+}
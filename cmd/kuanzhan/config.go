@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+	"pkg.blksails.net/kuanzhan"
+)
+
+var (
+	keyFile       string   // 加密口令密钥文件
+	configValue   string   // 加解密单个值
+	secretKeys    []string // 需要加密的配置项
+	encryptOnSave bool     // 写入前加密
+)
+
+// profileDefaults 可以在 profile 中配置默认值的参数，参数名 -> 配置项
+// 仅在命令行没有显式指定该参数时生效
+var profileDefaults = map[string]string{
+	"business-type": "business_type",
+	"tpl":           "page_tpl",
+	"prefix":        "domain_prefix",
+	"suffix":        "domain_suffix",
+	"rate-limit":    "rate_limit",
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "配置管理",
+	Long:  "管理 kuanzhan.yaml 配置文件",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var configEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "加密配置文件中的密钥",
+	Long:  "使用口令（KUANZHAN_PASSPHRASE 或 --key-file）加密配置文件中所有 profile 的 app_secret，加密后的值以 enc: 开头",
+	Run: func(cmd *cobra.Command, args []string) {
+		key, err := passphrase()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if configValue != "" {
+			value, err := kuanzhan.EncryptSecret(configValue, key)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println(value)
+			return
+		}
+
+		n, err := transformSecrets(func(value string) (string, error) {
+			if kuanzhan.IsEncrypted(value) {
+				return value, nil
+			}
+			return kuanzhan.EncryptSecret(value, key)
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Println("encrypted", n, "values in", viper.ConfigFileUsed())
+	},
+}
+
+var configDecryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "解密配置文件中的密钥",
+	Long:  "将配置文件中 enc: 开头的加密值还原为明文",
+	Run: func(cmd *cobra.Command, args []string) {
+		key, err := passphrase()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if configValue != "" {
+			value, err := kuanzhan.DecryptSecret(configValue, key)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println(value)
+			return
+		}
+
+		n, err := transformSecrets(func(value string) (string, error) {
+			return kuanzhan.DecryptSecret(value, key)
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Println("decrypted", n, "values in", viper.ConfigFileUsed())
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "读取配置项",
+	Long:  "读取配置文件中的配置项，使用点号分隔层级，例如 profiles.production.business_type",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		doc, err := loadConfigNode(configFilePath())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		node := lookupNode(doc, strings.Split(args[0], "."))
+		if node == nil {
+			log.Fatalf("config key %s not found", args[0])
+		}
+		if node.Kind == yaml.ScalarNode {
+			fmt.Println(node.Value)
+			return
+		}
+
+		b, err := yaml.Marshal(node)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(string(b))
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "设置配置项",
+	Long:  "设置配置文件中的配置项，使用点号分隔层级，例如 profiles.production.domain_suffix .shop",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := configFilePath()
+		doc, err := loadConfigNode(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		value := args[1]
+		if encryptOnSave {
+			if value, err = encryptValue(value); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		setNode(doc, strings.Split(args[0], "."), value)
+		if err := saveConfigNode(path, doc); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("set", args[0], "in", path)
+	},
+}
+
+func init() {
+	configSetCmd.Flags().BoolVar(&encryptOnSave, "encrypt", false, "加密后写入")
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+
+	rootCmd.PersistentFlags().StringVar(&keyFile, "key-file", "", "解密配置密钥的口令文件，默认读取 KUANZHAN_PASSPHRASE、KUANZHAN_KEY_FILE 或 ~/.kuanzhan/key")
+
+	for _, cmd := range []*cobra.Command{configEncryptCmd, configDecryptCmd} {
+		cmd.Flags().StringVar(&configValue, "value", "", "只处理指定的值并输出结果，不修改配置文件")
+		cmd.Flags().StringSliceVar(&secretKeys, "keys", []string{"app_secret"}, "需要处理的配置项")
+		configCmd.AddCommand(cmd)
+	}
+
+	rootCmd.AddCommand(configCmd)
+}
+
+// passphrase 加解密配置使用的口令
+func passphrase() ([]byte, error) {
+	if keyFile != "" {
+		return kuanzhan.ReadKeyFile(keyFile)
+	}
+	return kuanzhan.DefaultPassphrase()
+}
+
+// encryptValue 使用当前口令加密
+func encryptValue(value string) (string, error) {
+	key, err := passphrase()
+	if err != nil {
+		return "", err
+	}
+	return kuanzhan.EncryptSecret(value, key)
+}
+
+// configFilePath 当前配置文件路径，没有找到配置文件时使用 $HOME/.kuanzhan/kuanzhan.yaml
+func configFilePath() string {
+	if path := viper.ConfigFileUsed(); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "kuanzhan.yaml"
+	}
+	return filepath.Join(home, ".kuanzhan", "kuanzhan.yaml")
+}
+
+// profileString 读取当前 profile 的配置项，profile 未配置时使用顶级配置项
+func profileString(key string) string {
+	if v := viper.GetString(fmt.Sprintf("profiles.%s.%s", profile, key)); v != "" {
+		return v
+	}
+	return viper.GetString(key)
+}
+
+// applyProfileDefaults 使用 profile 中的默认值填充未显式指定的参数
+func applyProfileDefaults(cmd *cobra.Command) {
+	for flagName, key := range profileDefaults {
+		flag := cmd.Flags().Lookup(flagName)
+		if flag == nil || flag.Changed {
+			continue
+		}
+		if v := profileString(key); v != "" {
+			if err := flag.Value.Set(v); err != nil {
+				log.Fatalf("invalid %s %q in config: %v", key, v, err)
+			}
+		}
+	}
+}
+
+// transformSecrets 对配置文件中所有 secretKeys 配置项执行 fn 并写回，返回修改的数量
+func transformSecrets(fn func(string) (string, error)) (int, error) {
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		return 0, fmt.Errorf("no kuanzhan.yaml config file found")
+	}
+	doc, err := loadConfigNode(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	err = walkMappings(doc, func(key, value *yaml.Node) error {
+		if value.Kind != yaml.ScalarNode || value.Value == "" || !slices.Contains(secretKeys, key.Value) {
+			return nil
+		}
+		v, err := fn(value.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key.Value, err)
+		}
+		if v != value.Value {
+			value.Value = v
+			value.Style = 0
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if n > 0 {
+		if err := saveConfigNode(path, doc); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// loadConfigNode 读取配置文件为 yaml 节点，保留注释和顺序
+// 配置文件不存在时返回空文档
+func loadConfigNode(path string) (*yaml.Node, error) {
+	var doc yaml.Node
+	b, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if doc.Kind == 0 {
+		doc.Kind = yaml.DocumentNode
+	}
+	if len(doc.Content) == 0 {
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	return &doc, nil
+}
+
+// saveConfigNode 将 yaml 节点写回配置文件
+func saveConfigNode(path string, doc *yaml.Node) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	perm := os.FileMode(0600)
+	if fi, err := os.Stat(path); err == nil {
+		perm = fi.Mode().Perm()
+	} else if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), perm)
+}
+
+// lookupNode 按路径查找节点，不存在时返回 nil
+func lookupNode(doc *yaml.Node, path []string) *yaml.Node {
+	n := doc
+	if n.Kind == yaml.DocumentNode {
+		n = n.Content[0]
+	}
+	for _, key := range path {
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				next = n.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return n
+}
+
+// setNode 按路径设置标量值，自动创建中间的 mapping 节点
+func setNode(doc *yaml.Node, path []string, value string) {
+	parent := ensureMapping(doc, path[:len(path)-1])
+	key := path[len(path)-1]
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			n := parent.Content[i+1]
+			n.Kind, n.Tag, n.Value, n.Content, n.Style = yaml.ScalarNode, "!!str", value, nil, 0
+			return
+		}
+	}
+	parent.Content = append(parent.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value},
+	)
+}
+
+// ensureMapping 按路径查找 mapping 节点，不存在时创建
+func ensureMapping(doc *yaml.Node, path []string) *yaml.Node {
+	n := doc.Content[0]
+	for _, key := range path {
+		var next *yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				next = n.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, next)
+		}
+		if next.Kind != yaml.MappingNode {
+			next.Kind, next.Tag, next.Value, next.Content = yaml.MappingNode, "!!map", "", nil
+		}
+		n = next
+	}
+	return n
+}
+
+// deleteNode 按路径删除节点，返回是否存在
+func deleteNode(doc *yaml.Node, path []string) bool {
+	parent := lookupNode(doc, path[:len(path)-1])
+	if parent == nil || parent.Kind != yaml.MappingNode {
+		return false
+	}
+	key := path[len(path)-1]
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// walkMappings 递归遍历所有 mapping 节点的键值对
+func walkMappings(n *yaml.Node, fn func(key, value *yaml.Node) error) error {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if err := fn(n.Content[i], n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+	}
+	for _, c := range n.Content {
+		if err := walkMappings(c, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
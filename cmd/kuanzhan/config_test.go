@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testConfigYAML = `# kuanzhan config
+app_key: top_key # 默认账号
+app_secret: top_secret
+profiles:
+  production:
+    app_key: prod_key
+`
+
+func TestConfigNode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kuanzhan.yaml")
+	if err := os.WriteFile(path, []byte(testConfigYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := loadConfigNode(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := lookupNode(doc, []string{"profiles", "production", "app_key"}); n == nil || n.Value != "prod_key" {
+		t.Fatalf("lookup profiles.production.app_key: %v", n)
+	}
+
+	setNode(doc, []string{"profiles", "production", "domain_suffix"}, ".shop")
+	setNode(doc, []string{"profiles", "testing", "app_key"}, "test_key")
+	setNode(doc, []string{"current_profile"}, "testing")
+	if !deleteNode(doc, []string{"app_secret"}) {
+		t.Error("expected app_secret to be deleted")
+	}
+
+	if names := profileNames(doc); strings.Join(names, ",") != "default,production,testing" {
+		t.Errorf("unexpected profiles %v", names)
+	}
+
+	if err := saveConfigNode(path, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(b)
+	for _, want := range []string{"# kuanzhan config", "# 默认账号", "domain_suffix: .shop", "current_profile: testing", "app_key: test_key"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("saved config missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "top_secret") {
+		t.Errorf("app_secret not deleted:\n%s", out)
+	}
+}
+
+func TestLoadConfigNodeMissing(t *testing.T) {
+	doc, err := loadConfigNode(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setNode(doc, []string{"profiles", "default", "app_key"}, "key")
+	if n := lookupNode(doc, []string{"profiles", "default", "app_key"}); n == nil || n.Value != "key" {
+		t.Fatalf("lookup after set: %v", n)
+	}
+}
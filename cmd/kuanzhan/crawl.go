@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"pkg.blksails.net/kuanzhan"
+)
+
+var (
+	crawlOpts   = crawlOptions{Depth: 1, SameHost: true, MaxPages: 20}
+	crawlSiteId int    // import-site 的目标站点
+	crawlTpl    string // import-site 创建页面的模板
+	crawlDryRun bool   // import-site 只抓取，不创建页面
+)
+
+// crawlOptions 抓取源站点的选项
+type crawlOptions struct {
+	Depth    int  // 从起始页面跟随链接的层数，0 只抓取起始页面
+	SameHost bool // 只跟随与起始页面同主机的链接
+	MaxPages int  // 最多抓取的页面数，0 表示不限制
+}
+
+// crawledPage 抓取的一个源页面
+type crawledPage struct {
+	URL     string        // 源页面地址
+	Depth   int           // 距起始页面的链接层数
+	Page    *importedPage // 导入的内容
+	Name    string        // 页面名称，默认为源页面的 <title>
+	PageID  int           // 创建的快站页面
+	Content []byte        // 链接改写为快站页面地址后的内容
+}
+
+// importSiteRow import-site 输出的一行
+type importSiteRow struct {
+	Depth     int    `json:"depth" yaml:"depth"`
+	SourceURL string `json:"sourceUrl" yaml:"sourceUrl"`
+	PageID    int    `json:"pageId,omitempty" yaml:"pageId,omitempty"`
+	PageName  string `json:"pageName" yaml:"pageName"`
+	PageURL   string `json:"pageUrl,omitempty" yaml:"pageUrl,omitempty"`
+	Links     int    `json:"links" yaml:"links"`
+}
+
+var importSiteColumns = []outputColumn{
+	{"层级", "depth"},
+	{"源地址", "sourceUrl"},
+	{"页面ID", "pageId"},
+	{"页面名称", "pageName"},
+	{"页面地址", "pageUrl"},
+	{"链接数", "links"},
+}
+
+var importSiteCmd = &cobra.Command{
+	Use:   "import-site <url>",
+	Short: "导入多页面源站点",
+	Long: `从起始页面开始抓取 --depth 层链接的源页面，为每个源页面创建一个快站页面，
+页面之间的链接改写为对应快站页面的地址后上传。`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if crawlSiteId == 0 && !crawlDryRun {
+			log.Fatal("--site-id is required")
+		}
+
+		pages, err := crawlPages(args[0], crawlOpts, importOpts, fetchOpts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, p := range pages {
+			printAssetReport(p.Page)
+		}
+
+		var siteDomain string
+		if !crawlDryRun {
+			client := newClient()
+			siteDomain, err = importSite(client, crawlSiteId, pages, crawlTpl)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		rows := make([]importSiteRow, 0, len(pages))
+		for _, p := range pages {
+			row := importSiteRow{Depth: p.Depth, SourceURL: p.URL, PageID: p.PageID, PageName: p.Name, Links: len(p.Page.Links)}
+			if p.PageID != 0 {
+				row.PageURL = pageLink(siteDomain, p.PageID)
+			}
+			rows = append(rows, row)
+		}
+		if err := renderOutput(os.Stdout, importSiteColumns, rows); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	pflags := importSiteCmd.PersistentFlags()
+	pflags.IntVarP(&crawlSiteId, "site-id", "i", 0, "站点ID")
+	pflags.IntVar(&crawlOpts.Depth, "depth", crawlOpts.Depth, "从起始页面跟随链接的层数，0 只导入起始页面")
+	pflags.BoolVar(&crawlOpts.SameHost, "same-host", crawlOpts.SameHost, "只跟随与起始页面同主机的链接")
+	pflags.IntVar(&crawlOpts.MaxPages, "max-pages", crawlOpts.MaxPages, "最多导入的页面数，0 表示不限制")
+	pflags.StringVarP(&crawlTpl, "tpl", "t", "WHITE", "创建页面模板")
+	pflags.BoolVar(&crawlDryRun, "dry-run", false, "只抓取并列出源页面，不创建和上传页面")
+	addImportFlags(importSiteCmd)
+	rootCmd.AddCommand(importSiteCmd)
+}
+
+// crawlPages 从 startURL 开始按广度优先抓取源页面
+// 起始页面失败时返回错误，链接的页面失败时跳过，页面中指向它的链接保持不变
+func crawlPages(startURL string, opts crawlOptions, imp importOptions, fo fetchOptions) ([]*crawledPage, error) {
+	start, ok := normalizeLink(startURL)
+	if !ok {
+		return nil, fmt.Errorf("invalid url %q", startURL)
+	}
+	startHost := hostOf(start)
+
+	f, err := newFetcher(fo, start)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{start: true}
+	queue := []*crawledPage{{URL: start}}
+	var pages []*crawledPage
+	for len(queue) > 0 {
+		if opts.MaxPages > 0 && len(pages) >= opts.MaxPages {
+			log.Printf("reached --max-pages %d, %d pages not imported", opts.MaxPages, len(queue))
+			break
+		}
+		p := queue[0]
+		queue = queue[1:]
+
+		page, err := loadPage(f, p.URL, imp)
+		if err != nil {
+			if p.Depth == 0 {
+				return nil, err
+			}
+			log.Printf("warning: skip %s: %v", p.URL, err)
+			continue
+		}
+		if page.Charset != "utf-8" {
+			log.Printf("%s: transcoded from %s to utf-8", p.URL, page.Charset)
+		}
+		p.Page = page
+		p.Name = pageNameOf(p.URL, page.Title)
+		pages = append(pages, p)
+		if final, ok := normalizeLink(page.URL); ok {
+			seen[final] = true
+		}
+
+		if p.Depth >= opts.Depth {
+			continue
+		}
+		for _, link := range page.Links {
+			next, ok := normalizeLink(link)
+			if !ok || seen[next] || (opts.SameHost && hostOf(next) != startHost) {
+				continue
+			}
+			seen[next] = true
+			queue = append(queue, &crawledPage{URL: next, Depth: p.Depth + 1})
+		}
+	}
+	return pages, nil
+}
+
+// importSite 为每个抓取的页面创建快站页面，将页面之间的链接改写为快站页面地址后上传，返回站点域名
+// 页面创建后立即记录到状态文件，上传失败时已创建的页面仍然可以通过 state 找到
+func importSite(client *kuanzhan.Client, siteId int, pages []*crawledPage, tpl string) (string, error) {
+	info, err := client.GetSiteInfo(siteId)
+	if err != nil {
+		return "", fmt.Errorf("GetSiteInfo %d: %w", siteId, err)
+	}
+	siteDomain := info.Data.SiteDomain
+
+	targets := make(map[string]string)
+	for _, p := range pages {
+		resp, err := client.CreateSitePage(siteId, tpl)
+		if err != nil {
+			return "", fmt.Errorf("CreateSitePage %d: %w", siteId, err)
+		}
+		p.PageID = resp.Data.PageId
+		recordResources(newResource(resourcePage, "import-site", siteId, p.PageID, p.Name, ""))
+		if _, err := client.UpdatePageName(p.PageID, p.Name); err != nil {
+			return "", fmt.Errorf("UpdatePageName %d: %w", p.PageID, err)
+		}
+		log.Println("create page", p.PageID, "for", p.URL)
+
+		targets[p.URL] = pageLink(siteDomain, p.PageID)
+		if final, ok := normalizeLink(p.Page.URL); ok {
+			targets[final] = targets[p.URL]
+		}
+	}
+
+	pageIds := make([]int, 0, len(pages))
+	contents := make(map[int][]byte, len(pages))
+	sources := make(map[int]string, len(pages))
+	for _, p := range pages {
+		content, err := rewriteLinks(p.Page.Content, func(href string) string {
+			key, _ := normalizeLink(href)
+			return targets[key]
+		})
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", p.URL, err)
+		}
+		p.Content = content
+		pageIds = append(pageIds, p.PageID)
+		contents[p.PageID] = content
+		sources[p.PageID] = p.URL
+	}
+
+	if _, err := client.PublishSite(siteId); err != nil {
+		return "", fmt.Errorf("PublishSite %d: %w", siteId, err)
+	}
+	if err := uploadPages(client, siteId, pageIds, contents, sources); err != nil {
+		return "", err
+	}
+	for _, pageId := range pageIds {
+		recordContent([]int{pageId}, contentHash(contents[pageId]))
+	}
+	return siteDomain, nil
+}
+
+// uploadPages 上传站点中每个页面的内容并归档，sources 为每个页面内容的来源
+// 批量任务只能使用一份内容，内容不同时逐个修改并发布页面
+func uploadPages(client *kuanzhan.Client, siteId int, pageIds []int, contents map[int][]byte, sources map[int]string) error {
+	if len(pageIds) == 0 {
+		return nil
+	}
+	known := make(map[int]int, len(pageIds))
+	same := true
+	for _, pageId := range pageIds {
+		known[pageId] = siteId
+		same = same && bytes.Equal(contents[pageId], contents[pageIds[0]])
+	}
+
+	taskId := ""
+	if same {
+		resp, err := client.BatchModifyPagePublishPageJs([]int{siteId}, pageIds, string(contents[pageIds[0]]), true, "")
+		if err != nil {
+			return fmt.Errorf("BatchModifyPagePublishPageJs: %w", err)
+		}
+		taskId = resp.Data.TaskId
+		log.Println("taskId", taskId)
+	} else {
+		for _, pageId := range pageIds {
+			if _, err := client.ModifyPageJs(siteId, strconv.Itoa(pageId), string(contents[pageId]), false); err != nil {
+				return fmt.Errorf("ModifyPageJs %d: %w", pageId, err)
+			}
+			if _, err := client.PublishPage(siteId, pageId); err != nil {
+				return fmt.Errorf("PublishPage %d: %w", pageId, err)
+			}
+			log.Println("upload page", pageId, "to site", siteId)
+		}
+	}
+
+	for _, pageId := range pageIds {
+		if err := archiveUpload(client, contents[pageId], sources[pageId], taskId, []int{siteId}, []int{pageId}, known); err != nil {
+			log.Println("warning: archive upload:", err)
+		}
+	}
+	return nil
+}
+
+// rewriteLinks 将 <a> 链接改写为 target 返回的地址，保留锚点，target 返回空字符串时不改写
+// content 可以是 body 的内部内容，也可以是完整的 HTML 文档
+func rewriteLinks(content []byte, target func(href string) string) ([]byte, error) {
+	var nodes []*html.Node
+	if isDocument(content) {
+		doc, err := html.Parse(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		nodes = []*html.Node{doc}
+	} else {
+		body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+		fragment, err := html.ParseFragment(bytes.NewReader(content), body)
+		if err != nil {
+			return nil, err
+		}
+		nodes = fragment
+	}
+
+	changed := false
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A {
+			for i, a := range n.Attr {
+				if a.Key != "href" || strings.HasPrefix(a.Val, "#") {
+					continue
+				}
+				link := target(a.Val)
+				if link == "" {
+					continue
+				}
+				if u, err := url.Parse(a.Val); err == nil && u.Fragment != "" {
+					link += "#" + u.Fragment
+				}
+				n.Attr[i].Val = link
+				changed = true
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	if !changed {
+		return content, nil
+	}
+
+	var buf bytes.Buffer
+	for _, n := range nodes {
+		if err := html.Render(&buf, n); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// isDocument 内容是否为以 <!DOCTYPE> 或 <html> 开始的完整文档
+func isDocument(content []byte) bool {
+	head := strings.ToLower(string(bytes.TrimSpace(content[:min(len(content), 64)])))
+	return strings.HasPrefix(head, "<!doctype") || strings.HasPrefix(head, "<html")
+}
+
+// normalizeLink 去掉锚点的 http(s) 绝对地址，用于判断两个链接是否指向同一页面
+func normalizeLink(raw string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	u.Fragment = ""
+	u.Host = strings.ToLower(u.Host)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String(), true
+}
+
+func hostOf(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// pageNameOf 页面名称，源页面没有 <title> 时使用地址的路径
+func pageNameOf(link, title string) string {
+	if title != "" {
+		return title
+	}
+	u, err := url.Parse(link)
+	if err != nil || u.Path == "/" {
+		return link
+	}
+	return strings.TrimPrefix(u.Path, "/")
+}
+
+// pageLink 页面的绝对地址，站点域名没有协议时使用 https
+func pageLink(siteDomain string, pageId int) string {
+	link := pageURL(siteDomain, pageId)
+	if !strings.Contains(link, "://") {
+		link = "https://" + link
+	}
+	return link
+}
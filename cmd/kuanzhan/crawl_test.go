@@ -0,0 +1,137 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportSite(t *testing.T) {
+	oldState, oldInventory, oldProfile := stateFilePath, inventoryFile, profile
+	t.Cleanup(func() { stateFilePath, inventoryFile, profile = oldState, oldInventory, oldProfile })
+	dir := t.TempDir()
+	stateFilePath = filepath.Join(dir, "state.json")
+	inventoryFile = filepath.Join(dir, "inventory.db")
+	profile = "default"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<html><head><title>首页</title></head><body>` +
+				`<a href="a.html">a</a><a href="/b.html#form">b</a><a href="/a.html#top">a</a>` +
+				`<a href="https://other.example/x">x</a><a href="/missing">m</a><a href="/logo.png">logo</a></body></html>`))
+		case "/a.html":
+			w.Write([]byte(`<html><head><title>活动页</title></head><body><a href="/">home</a><a href="c.html">c</a></body></html>`))
+		case "/b.html":
+			w.Write([]byte(`<html><body><a href="/old">a</a></body></html>`))
+		case "/old":
+			http.Redirect(w, r, "/a.html", http.StatusMovedPermanently)
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	fetch := fetchOptions{Timeout: 5 * time.Second, MaxRedirects: 10}
+	pages, err := crawlPages(srv.URL, crawlOptions{Depth: 1, SameHost: true}, importOptions{}, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range pages {
+		got = append(got, strings.TrimPrefix(p.URL, srv.URL)+" "+p.Name+" "+strconv.Itoa(p.Depth))
+	}
+	// 外部链接、404 和图片跳过，c.html 超过 --depth
+	if want := "/ 首页 0,/a.html 活动页 1,/b.html b.html 1"; strings.Join(got, ",") != want {
+		t.Fatalf("crawl = %q, want %q", got, want)
+	}
+
+	fake := &fakeKuanzhan{sites: map[int]*fakeSite{
+		1: {Name: "a", Domain: "a.kuaizhan.com", Pages: map[int]string{}},
+	}}
+	client := newTestServer(t, fake.ServeHTTP)
+
+	domain, err := importSite(client, 1, pages, "WHITE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if domain != "a.kuaizhan.com" {
+		t.Errorf("domain = %s", domain)
+	}
+	home, a, b := pages[0].PageID, pages[1].PageID, pages[2].PageID
+	site := fake.sites[1]
+	if site.Pages[home] != "首页" || site.Pages[a] != "活动页" || site.Pages[b] != "b.html" {
+		t.Errorf("page names: %v", site.Pages)
+	}
+
+	link := func(pageId int) string { return "https://a.kuaizhan.com/" + strconv.Itoa(pageId) }
+	for pageId, wants := range map[int][]string{
+		home: {
+			`<a href="` + link(a) + `">a</a>`,
+			`<a href="` + link(b) + `#form">b</a>`,
+			`<a href="` + link(a) + `#top">a</a>`,
+			`<a href="https://other.example/x">`,
+			`<a href="` + srv.URL + `/missing">`,
+		},
+		a: {`<a href="` + link(home) + `">home</a>`, `<a href="` + srv.URL + `/c.html">c</a>`},
+		// 重定向前的地址不在抓取结果中，保持不变
+		b: {`<a href="` + srv.URL + `/old">`},
+	} {
+		for _, want := range wants {
+			if !strings.Contains(site.Contents[pageId], want) {
+				t.Errorf("page %d missing %s:\n%s", pageId, want, site.Contents[pageId])
+			}
+		}
+		if site.Published[pageId] != 1 {
+			t.Errorf("page %d published %d times", pageId, site.Published[pageId])
+		}
+	}
+
+	hashes, err := uploadedHashes(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashes[home] != contentHash(pages[0].Content) || len(hashes) != 3 {
+		t.Errorf("archived hashes: %v", hashes)
+	}
+	s, err := loadState(stateFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Resources) != 3 || s.Resources[0].PageID != home || s.Resources[0].Command != "import-site" || s.Resources[0].ContentHash != contentHash(pages[0].Content) {
+		t.Errorf("state: %+v", s.Resources)
+	}
+
+	// 上传失败时已创建的页面仍然记录在状态文件中
+	failing := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/publishSite") {
+			fake.fail(w, "publish error")
+			return
+		}
+		fake.ServeHTTP(w, r)
+	})
+	if _, err := importSite(failing, 1, pages[:1], "WHITE"); err == nil {
+		t.Fatal("expected error when PublishSite fails")
+	}
+	if s, err = loadState(stateFilePath); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Resources) != 4 || s.Resources[3].PageID != pages[0].PageID || s.Resources[3].ContentHash != "" {
+		t.Errorf("state after failure: %+v", s.Resources)
+	}
+
+	if _, err := crawlPages(srv.URL+"/missing", crawlOptions{}, importOptions{}, fetch); err == nil {
+		t.Error("expected error when the start page fails")
+	}
+	pages, err = crawlPages(srv.URL, crawlOptions{Depth: 2, SameHost: true, MaxPages: 2}, importOptions{}, fetch)
+	if err != nil || len(pages) != 2 {
+		t.Errorf("--max-pages: %d pages, %v", len(pages), err)
+	}
+}
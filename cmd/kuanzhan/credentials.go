@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"pkg.blksails.net/kuanzhan"
+)
+
+// credentialsCmd 显示当前使用的凭证及其来源
+var credentialsCmd = &cobra.Command{
+	Use:   "credentials",
+	Short: "显示凭证来源",
+	Long:  "按凭证链（参数 -> 环境变量 -> 配置文件 -> credential_process）解析凭证，并显示每个凭证的来源",
+	Run: func(cmd *cobra.Command, args []string) {
+		creds, err := credentialProvider().Retrieve()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.Header("凭证", "值", "来源")
+		table.Append("app_key", creds.AppKey, creds.AppKeySource)
+		table.Append("app_secret", maskSecret(creds.AppSecret), creds.AppSecretSource)
+		table.Render()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(credentialsCmd)
+}
+
+// credentialProvider 当前 profile 的凭证链
+func credentialProvider() kuanzhan.CredentialProvider {
+	return kuanzhan.NewChainProvider(
+		&kuanzhan.StaticProvider{AppKey: appKey, AppSecret: appSecret},
+		&kuanzhan.EnvProvider{},
+		&kuanzhan.FileProvider{Path: viper.ConfigFileUsed(), Profile: profile, Passphrase: passphrase},
+	)
+}
+
+// maskSecret 只保留首尾各4个字符
+func maskSecret(s string) string {
+	if len(s) <= 8 {
+		return strings.Repeat("*", len(s))
+	}
+	return fmt.Sprintf("%s%s%s", s[:4], strings.Repeat("*", len(s)-8), s[len(s)-4:])
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"gopkg.in/yaml.v3"
+	"pkg.blksails.net/kuanzhan"
+)
+
+var (
+	deploySiteId  int    // deploy-dir 的目标站点，默认使用映射文件中的站点
+	deployMapFile string // deploy-dir 的映射文件
+	deployTpl     string // deploy-dir 创建页面的模板
+	deployCharset string // deploy-dir 本地文件的编码
+)
+
+// deployMapping deploy-dir 的映射文件，目录中的文件到页面
+type deployMapping struct {
+	SiteID int                    `yaml:"siteId"`
+	Pages  map[string]*deployPage `yaml:"pages"`
+}
+
+// deployPage 映射文件中的一个页面，pageId 为 0 时创建新页面
+type deployPage struct {
+	PageID int    `yaml:"pageId"`
+	Name   string `yaml:"name,omitempty"`
+}
+
+// deployRow deploy-dir 输出的一行
+type deployRow struct {
+	File     string `json:"file" yaml:"file"`
+	PageID   int    `json:"pageId" yaml:"pageId"`
+	PageName string `json:"pageName" yaml:"pageName"`
+	PageURL  string `json:"pageUrl" yaml:"pageUrl"`
+	Action   string `json:"action" yaml:"action"`
+}
+
+var deployColumns = []outputColumn{
+	{"文件", "file"},
+	{"页面ID", "pageId"},
+	{"页面名称", "pageName"},
+	{"页面地址", "pageUrl"},
+	{"操作", "action"},
+}
+
+// deploy-dir 对页面的操作
+const (
+	deployCreated = "created"
+	deployUpdated = "updated"
+)
+
+var deployDirCmd = &cobra.Command{
+	Use:   "deploy-dir <dir>",
+	Short: "部署本地目录",
+	Long: `将目录中的每个 HTML 文件上传到站点的一个页面。
+
+映射文件（默认 <dir>/pages.yaml）记录文件到页面ID和页面名称的映射，没有映射的文件创建新页面，
+名称为文件的 <title>，没有时使用文件名。部署后写回映射文件，再次部署时更新相同的页面。
+文件之间的相对链接改写为对应快站页面的地址，与 import-site 相同的方式上传。`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := args[0]
+		mapFile := cmp.Or(deployMapFile, filepath.Join(dir, "pages.yaml"))
+
+		mapping, err := loadDeployMapping(mapFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		client := newClient()
+		rows, err := deployDir(client, dir, deploySiteId, mapping, mapFile, deployTpl, deployCharset)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := renderOutput(os.Stdout, deployColumns, rows); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	pflags := deployDirCmd.PersistentFlags()
+	pflags.IntVarP(&deploySiteId, "site-id", "i", 0, "站点ID，默认使用映射文件中的站点")
+	pflags.StringVarP(&deployMapFile, "mapping", "m", "", "映射文件，默认为 <dir>/pages.yaml")
+	pflags.StringVarP(&deployTpl, "tpl", "t", "WHITE", "创建页面模板")
+	pflags.StringVar(&deployCharset, "charset", "", "本地文件编码，如 gbk、gb18030，默认根据 meta 和内容检测")
+	rootCmd.AddCommand(deployDirCmd)
+}
+
+// loadDeployMapping 读取映射文件，文件不存在时返回空映射
+func loadDeployMapping(path string) (*deployMapping, error) {
+	mapping := &deployMapping{Pages: make(map[string]*deployPage)}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return mapping, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(b, mapping); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if mapping.Pages == nil {
+		mapping.Pages = make(map[string]*deployPage)
+	}
+	for file, page := range mapping.Pages {
+		if page == nil {
+			mapping.Pages[file] = &deployPage{}
+		}
+	}
+	return mapping, nil
+}
+
+// saveDeployMapping 写回映射文件
+func saveDeployMapping(path string, mapping *deployMapping) error {
+	b, err := yaml.Marshal(mapping)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// deployFiles 目录中的 HTML 文件，返回以 / 分隔的相对路径
+func deployFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".html", ".htm":
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+// deployDir 将目录中的文件上传到站点的页面，创建页面后写回映射文件
+func deployDir(client *kuanzhan.Client, dir string, siteId int, mapping *deployMapping, mapFile, tpl, charset string) ([]deployRow, error) {
+	switch {
+	case siteId == 0 && mapping.SiteID == 0:
+		return nil, fmt.Errorf("--site-id is required")
+	case siteId == 0:
+		siteId = mapping.SiteID
+	case mapping.SiteID != 0 && mapping.SiteID != siteId:
+		return nil, fmt.Errorf("%s maps pages of site %d, not %d", mapFile, mapping.SiteID, siteId)
+	}
+	mapping.SiteID = siteId
+
+	files, err := deployFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no html files in %s", dir)
+	}
+	owners := make(map[int]string)
+	for _, file := range files {
+		if page := mapping.Pages[file]; page != nil && page.PageID != 0 {
+			if other, ok := owners[page.PageID]; ok {
+				return nil, fmt.Errorf("%s: %s and %s are both mapped to page %d", mapFile, other, file, page.PageID)
+			}
+			owners[page.PageID] = file
+		}
+	}
+	for file := range mapping.Pages {
+		if !slices.Contains(files, file) {
+			log.Printf("warning: %s is mapped to page %d but does not exist", file, mapping.Pages[file].PageID)
+		}
+	}
+
+	contents := make(map[string][]byte, len(files))
+	for _, file := range files {
+		content, err := readLocalPage(filepath.Join(dir, filepath.FromSlash(file)), charset)
+		if err != nil {
+			return nil, err
+		}
+		contents[file] = content
+	}
+
+	info, err := client.GetSiteInfo(siteId)
+	if err != nil {
+		return nil, fmt.Errorf("GetSiteInfo %d: %w", siteId, err)
+	}
+	siteDomain := info.Data.SiteDomain
+
+	rows := make([]deployRow, 0, len(files))
+	var created []*stateResource
+	err = func() error {
+		for _, file := range files {
+			page := mapping.Pages[file]
+			if page == nil {
+				page = &deployPage{}
+				mapping.Pages[file] = page
+			}
+			if page.Name == "" {
+				page.Name = cmp.Or(htmlTitle(contents[file]), strings.TrimSuffix(path.Base(file), path.Ext(file)))
+			}
+
+			action := deployUpdated
+			if page.PageID == 0 {
+				resp, err := client.CreateSitePage(siteId, tpl)
+				if err != nil {
+					return fmt.Errorf("CreateSitePage %d: %w", siteId, err)
+				}
+				page.PageID = resp.Data.PageId
+				action = deployCreated
+				log.Println("create page", page.PageID, "for", file)
+				created = append(created, newResource(resourcePage, "deploy-dir", siteId, page.PageID, page.Name, ""))
+			}
+			if _, err := client.UpdatePageName(page.PageID, page.Name); err != nil {
+				return fmt.Errorf("UpdatePageName %d: %w", page.PageID, err)
+			}
+			rows = append(rows, deployRow{File: file, PageID: page.PageID, PageName: page.Name, PageURL: pageLink(siteDomain, page.PageID), Action: action})
+		}
+		return nil
+	}()
+	// 创建页面后立即写回，失败后重新部署时使用已创建的页面
+	if saveErr := saveDeployMapping(mapFile, mapping); saveErr != nil {
+		return nil, errors.Join(err, fmt.Errorf("write %s: %w", mapFile, saveErr))
+	}
+	recordResources(created...)
+	if err != nil {
+		return nil, err
+	}
+
+	pageIds := make([]int, 0, len(files))
+	byPage := make(map[int][]byte, len(files))
+	sources := make(map[int]string, len(files))
+	for _, file := range files {
+		pageId := mapping.Pages[file].PageID
+		content, err := rewriteLinks(contents[file], func(href string) string {
+			if target, ok := localLinkTarget(file, href); ok && slices.Contains(files, target) {
+				return pageLink(siteDomain, mapping.Pages[target].PageID)
+			}
+			return ""
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		pageIds = append(pageIds, pageId)
+		byPage[pageId] = content
+		sources[pageId], _ = filepath.Abs(filepath.Join(dir, filepath.FromSlash(file)))
+	}
+
+	if _, err := client.PublishSite(siteId); err != nil {
+		return nil, fmt.Errorf("PublishSite %d: %w", siteId, err)
+	}
+	if err := uploadPages(client, siteId, pageIds, byPage, sources); err != nil {
+		return nil, err
+	}
+
+	for _, pageId := range pageIds {
+		recordContent([]int{pageId}, contentHash(byPage[pageId]))
+	}
+	return rows, nil
+}
+
+// localLinkTarget 将文件 from 中的链接解析为目录中的文件路径，不是本地链接时返回 false
+func localLinkTarget(from, href string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", false
+	}
+	if strings.HasPrefix(u.Path, "/") {
+		// 以 / 开始的链接相对于目录
+		return path.Clean(u.Path[1:]), true
+	}
+	return path.Join(path.Dir(from), u.Path), true
+}
+
+// htmlTitle 页面的 <title>
+func htmlTitle(content []byte) string {
+	doc, err := html.Parse(bytes.NewReader(content))
+	if err != nil {
+		return ""
+	}
+	if title := findNode(doc, atom.Title); title != nil && title.FirstChild != nil {
+		return strings.TrimSpace(title.FirstChild.Data)
+	}
+	return ""
+}
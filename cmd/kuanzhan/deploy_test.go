@@ -0,0 +1,130 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDeployDir(t *testing.T) {
+	oldState, oldInventory, oldProfile := stateFilePath, inventoryFile, profile
+	t.Cleanup(func() { stateFilePath, inventoryFile, profile = oldState, oldInventory, oldProfile })
+	tmp := t.TempDir()
+	stateFilePath = filepath.Join(tmp, "state.json")
+	inventoryFile = filepath.Join(tmp, "inventory.db")
+	profile = "default"
+
+	dir := filepath.Join(tmp, "site")
+	files := map[string]string{
+		"index.html":    `<!DOCTYPE html><html><head><title>首页</title></head><body><a href="about.html#form">about</a><a href="/sub/c.html">c</a><a href="https://example.com/">x</a></body></html>`,
+		"about.html":    `<p><a href="index.html">home</a><a href="missing.html">m</a></p>`,
+		"sub/c.html":    `<html><head><title>活动</title></head><body><a href="../index.html">home</a></body></html>`,
+		"notes.txt":     "not a page",
+		".git/x.html":   "hidden",
+		"pages.yaml":    "siteId: 1\npages:\n  index.html:\n    pageId: 10\n    name: 主页\n  old.html:\n    pageId: 11\n",
+		"sub/style.css": "p {}",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fake := &fakeKuanzhan{sites: map[int]*fakeSite{
+		1: {Name: "a", Domain: "a.kuaizhan.com", Pages: map[int]string{10: "首页", 11: "旧页面"}},
+	}}
+	client := newTestServer(t, fake.ServeHTTP)
+
+	mapFile := filepath.Join(dir, "pages.yaml")
+	deploy := func(siteId int) ([]deployRow, error) {
+		mapping, err := loadDeployMapping(mapFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return deployDir(client, dir, siteId, mapping, mapFile, "WHITE", "")
+	}
+
+	if _, err := deploy(2); err == nil {
+		t.Error("expected error deploying a mapping of site 1 to site 2")
+	}
+
+	rows, err := deploy(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, row := range rows {
+		got = append(got, row.File+" "+row.PageName+" "+row.Action)
+	}
+	if want := "about.html about created,index.html 主页 updated,sub/c.html 活动 created"; strings.Join(got, ",") != want {
+		t.Fatalf("rows = %q, want %q", got, want)
+	}
+	about, c := rows[0].PageID, rows[2].PageID
+
+	mapping, err := loadDeployMapping(mapFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mapping.SiteID != 1 || mapping.Pages["about.html"].PageID != about || mapping.Pages["sub/c.html"].PageID != c || mapping.Pages["old.html"].PageID != 11 {
+		t.Errorf("mapping not written back: %+v", mapping)
+	}
+
+	site := fake.sites[1]
+	link := func(pageId int) string { return "https://a.kuaizhan.com/" + strconv.Itoa(pageId) }
+	for pageId, wants := range map[int][]string{
+		10:    {"<title>首页</title>", `<a href="` + link(about) + `#form">`, `<a href="` + link(c) + `">`, `<a href="https://example.com/">`},
+		about: {`<a href="` + link(10) + `">`, `<a href="missing.html">`},
+		c:     {`<a href="` + link(10) + `">`},
+	} {
+		for _, want := range wants {
+			if !strings.Contains(site.Contents[pageId], want) {
+				t.Errorf("page %d missing %s:\n%s", pageId, want, site.Contents[pageId])
+			}
+		}
+	}
+	if site.Pages[10] != "主页" || site.Pages[about] != "about" || len(site.Pages) != 4 {
+		t.Errorf("pages: %v", site.Pages)
+	}
+
+	// 再次部署更新相同的页面
+	rows, err = deploy(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if row.Action != deployUpdated {
+			t.Errorf("redeploy: %+v", row)
+		}
+	}
+	if len(site.Pages) != 4 || site.Published[about] != 2 {
+		t.Errorf("redeploy pages %v, published %v", site.Pages, site.Published)
+	}
+
+	s, err := loadState(stateFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := slices.IndexFunc(s.Resources, func(r *stateResource) bool { return r.PageID == about })
+	if i < 0 || s.Resources[i].Command != "deploy-dir" || s.Resources[i].ContentHash != contentHash([]byte(site.Contents[about])) {
+		t.Errorf("state: %+v", s.Resources)
+	}
+
+	// 内容相同的页面使用一个批量任务
+	single := filepath.Join(tmp, "single")
+	os.MkdirAll(single, 0755)
+	os.WriteFile(filepath.Join(single, "a.html"), []byte("<p>a</p>"), 0644)
+	mapping = &deployMapping{Pages: map[string]*deployPage{}}
+	if _, err := deployDir(client, single, 1, mapping, filepath.Join(single, "pages.yaml"), "WHITE", ""); err != nil {
+		t.Fatal(err)
+	}
+	if pageId := mapping.Pages["a.html"].PageID; site.Contents[pageId] != "<p>a</p>" || fake.calls[len(fake.calls)-1] != "batchModifyPublishPageJs" {
+		t.Errorf("batch upload: %q, calls %v", site.Contents[pageId], fake.calls)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// 变化类型
+const (
+	changeAdded       = "added"        // 新增站点
+	changeRemoved     = "removed"      // 站点消失
+	changeStatus      = "status"       // 站点状态变化
+	changeDomain      = "domain"       // 站点域名变化
+	changePackage     = "package"      // 套餐类型变化
+	changeName        = "name"         // 站点名称变化
+	changePageAdded   = "page-added"   // 新增页面
+	changePageRemoved = "page-removed" // 页面消失
+	changePageRenamed = "page-renamed" // 页面名称变化
+)
+
+var (
+	diffSince string   // 起始时间
+	diffTypes []string // 只显示指定类型的变化
+)
+
+// changeRow 两次同步之间检测到的变化
+type changeRow struct {
+	Account    string `json:"account,omitempty" yaml:"account,omitempty"`
+	DetectedAt string `json:"detectedAt" yaml:"detectedAt"`
+	SiteID     int    `json:"siteId" yaml:"siteId"`
+	PageID     int    `json:"pageId,omitempty" yaml:"pageId,omitempty"`
+	Change     string `json:"change" yaml:"change"`
+	Old        string `json:"old" yaml:"old"`
+	New        string `json:"new" yaml:"new"`
+}
+
+var changeColumns = []outputColumn{
+	{"检测时间", "detectedAt"},
+	{"站点ID", "siteId"},
+	{"页面ID", "pageId"},
+	{"变化", "change"},
+	{"原值", "old"},
+	{"新值", "new"},
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "站点变化报告",
+	Long: `比较本地清单中的同步快照，报告指定时间以来站点的新增和消失、状态变化（如被封禁或下线）、域名、套餐、名称变化以及页面的新增、消失和改名。
+快照由 kuanzhan sync 记录，报告只包含同步时检测到的变化。`,
+	Run: func(cmd *cobra.Command, args []string) {
+		since, err := parseSince(diffSince, time.Now())
+		if err != nil {
+			log.Fatalf("invalid --since: %v", err)
+		}
+
+		inv, err := openInventory(inventoryPath())
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer inv.Close()
+
+		rows := []changeRow{}
+		for _, account := range inventoryAccounts() {
+			snapshots, err := inv.Snapshots(account, since)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if len(snapshots) == 0 {
+				log.Printf("profile %s has never been synced, run kuanzhan sync", account)
+			}
+			for i := 1; i < len(snapshots); i++ {
+				for _, row := range diffSnapshots(snapshots[i-1].Sites, snapshots[i].Sites, snapshots[i].Time) {
+					if len(diffTypes) > 0 && !slices.Contains(diffTypes, row.Change) {
+						continue
+					}
+					if multiProfile() {
+						row.Account = account
+					}
+					rows = append(rows, row)
+				}
+			}
+		}
+
+		columns := changeColumns
+		if multiProfile() {
+			columns = slices.Concat([]outputColumn{accountColumn}, columns)
+		}
+		if err := renderOutput(os.Stdout, columns, rows); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	diffCmd.Flags().StringVar(&diffSince, "since", "24h", "起始时间，如 24h、7d、2024-06-01")
+	diffCmd.Flags().StringSliceVar(&diffTypes, "type", nil, "只显示指定类型的变化: added,removed,status,domain,package,name,page-added,page-removed,page-renamed")
+	rootCmd.AddCommand(diffCmd)
+}
+
+// parseSince 解析起始时间，支持 Go duration、天数（7d）和日期时间
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateTime, time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a duration or date", s)
+}
+
+// diffSnapshots 比较两次快照，返回按站点ID排序的变化
+// 获取失败的站点没有站点信息，不比较其字段
+func diffSnapshots(old, new []*siteRecord, at time.Time) []changeRow {
+	detectedAt := at.Local().Format(time.DateTime)
+	oldSites := make(map[int]*siteRecord, len(old))
+	for _, rec := range old {
+		oldSites[rec.SiteID] = rec
+	}
+	newSites := make(map[int]*siteRecord, len(new))
+	for _, rec := range new {
+		newSites[rec.SiteID] = rec
+	}
+
+	var siteIds []int
+	for siteId := range oldSites {
+		siteIds = append(siteIds, siteId)
+	}
+	for siteId := range newSites {
+		if oldSites[siteId] == nil {
+			siteIds = append(siteIds, siteId)
+		}
+	}
+	slices.Sort(siteIds)
+
+	rows := []changeRow{}
+	add := func(siteId, pageId int, change, o, n string) {
+		rows = append(rows, changeRow{DetectedAt: detectedAt, SiteID: siteId, PageID: pageId, Change: change, Old: o, New: n})
+	}
+	for _, siteId := range siteIds {
+		o, n := oldSites[siteId], newSites[siteId]
+		switch {
+		case o == nil:
+			add(siteId, 0, changeAdded, "", n.SiteDomain)
+			continue
+		case n == nil:
+			add(siteId, 0, changeRemoved, o.SiteDomain, "")
+			continue
+		case o.SiteName == "" && o.Error != "", n.SiteName == "" && n.Error != "":
+			continue
+		}
+
+		for _, field := range []struct{ change, old, new string }{
+			{changeStatus, o.SiteStatus, n.SiteStatus},
+			{changeDomain, o.SiteDomain, n.SiteDomain},
+			{changePackage, o.PackageName, n.PackageName},
+			{changeName, o.SiteName, n.SiteName},
+		} {
+			if field.old != field.new {
+				add(siteId, 0, field.change, field.old, field.new)
+			}
+		}
+
+		// 页面获取失败时 Pages 为空，不比较页面
+		if o.Pages == nil || n.Pages == nil {
+			continue
+		}
+		oldPages := make(map[int]string, len(o.Pages))
+		for _, page := range o.Pages {
+			oldPages[page.PageID] = page.Title
+		}
+		newPages := make(map[int]string, len(n.Pages))
+		for _, page := range n.Pages {
+			newPages[page.PageID] = page.Title
+			if title, ok := oldPages[page.PageID]; !ok {
+				add(siteId, page.PageID, changePageAdded, "", page.Title)
+			} else if title != page.Title {
+				add(siteId, page.PageID, changePageRenamed, title, page.Title)
+			}
+		}
+		for _, page := range o.Pages {
+			if _, ok := newPages[page.PageID]; !ok {
+				add(siteId, page.PageID, changePageRemoved, page.Title, "")
+			}
+		}
+	}
+	return rows
+}
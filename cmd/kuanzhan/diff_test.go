@@ -0,0 +1,107 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	old := []*siteRecord{
+		{SiteID: 1, SiteName: "a", SiteDomain: "a.kuaizhan.com", SiteStatus: "ONLINE", Pages: []pageRecord{{10, "首页"}, {11, "活动"}}},
+		{SiteID: 2, SiteName: "b", SiteDomain: "b.kuaizhan.com", SiteStatus: "ONLINE", Pages: []pageRecord{}},
+		{SiteID: 4, SiteName: "d", SiteStatus: "ONLINE"},
+	}
+	new := []*siteRecord{
+		{SiteID: 1, SiteName: "a", SiteDomain: "a2.kuaizhan.com", SiteStatus: "BANNED", Pages: []pageRecord{{10, "新首页"}, {12, "新页面"}}},
+		{SiteID: 3, SiteName: "c", SiteDomain: "c.kuaizhan.com"},
+		{SiteID: 4, Error: "GetSiteInfo: timeout"},
+	}
+
+	got := diffSnapshots(old, new, time.Now())
+	want := []struct {
+		siteId, pageId int
+		change         string
+	}{
+		{1, 0, changeStatus},
+		{1, 0, changeDomain},
+		{1, 10, changePageRenamed},
+		{1, 12, changePageAdded},
+		{1, 11, changePageRemoved},
+		{2, 0, changeRemoved},
+		{3, 0, changeAdded},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d changes: %+v", len(got), got)
+	}
+	for i, w := range want {
+		if got[i].SiteID != w.siteId || got[i].PageID != w.pageId || got[i].Change != w.change {
+			t.Errorf("change %d = %+v, want %+v", i, got[i], w)
+		}
+	}
+	if got[0].Old != "ONLINE" || got[0].New != "BANNED" {
+		t.Errorf("status change: %+v", got[0])
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.Local)
+	tests := map[string]time.Time{
+		"24h":        now.Add(-24 * time.Hour),
+		"7d":         now.AddDate(0, 0, -7),
+		"2024-06-01": time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local),
+	}
+	for s, want := range tests {
+		got, err := parseSince(s, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseSince(%q) = %v, %v; want %v", s, got, err, want)
+		}
+	}
+	if _, err := parseSince("yesterday", now); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	inv, err := openInventory(filepath.Join(t.TempDir(), "inventory.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inv.Close()
+
+	t0 := time.Now().Add(-72 * time.Hour)
+	v1 := []*siteRecord{{SiteID: 1, SiteStatus: "ONLINE", FetchedAt: t0}}
+	v2 := []*siteRecord{{SiteID: 1, SiteStatus: "OFFLINE", FetchedAt: t0}}
+	for i, records := range [][]*siteRecord{v1, v1, v2, v2} {
+		// 内容相同的快照不重复记录
+		records[0].FetchedAt = t0.Add(time.Duration(i) * 24 * time.Hour)
+		if err := inv.PutSnapshot("prod", t0.Add(time.Duration(i)*24*time.Hour), records); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := inv.Snapshots("prod", t0.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("got %d snapshots", len(all))
+	}
+
+	// since 之前的最后一个快照作为起始状态
+	recent, err := inv.Snapshots("prod", t0.Add(36*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 2 || recent[0].Sites[0].SiteStatus != "ONLINE" {
+		t.Errorf("recent: %+v", recent)
+	}
+
+	latest, err := inv.Snapshots("prod", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(latest) != 1 || latest[0].Sites[0].SiteStatus != "OFFLINE" {
+		t.Errorf("latest: %+v", latest)
+	}
+}
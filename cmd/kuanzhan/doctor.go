@@ -0,0 +1,294 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+	"pkg.blksails.net/kuanzhan"
+)
+
+// 检查结果状态
+const (
+	checkPass = "PASS"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+	checkSkip = "SKIP"
+)
+
+// maxClockSkew 本地时钟与服务器时间允许的最大偏差
+const maxClockSkew = 5 * time.Minute
+
+var (
+	bundlePath    string        // 支持包输出路径
+	doctorTimeout time.Duration // 网络检查超时时间
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "诊断凭证和网络",
+	Long:  "检查配置文件、凭证、本地时钟、快站接口连通性和签名认证，输出诊断报告",
+	Run: func(cmd *cobra.Command, args []string) {
+		report := runDoctor("")
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.Header("检查项", "结果", "详情")
+		for _, r := range report.Checks {
+			table.Append(r.Name, r.Status, r.Detail)
+		}
+		table.Render()
+
+		if bundlePath != "" {
+			if err := writeSupportBundle(bundlePath, report); err != nil {
+				log.Fatal(err)
+			}
+			log.Println("support bundle written to", bundlePath)
+		}
+
+		if report.failed() {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	doctorCmd.Flags().StringVar(&bundlePath, "bundle", "", "输出脱敏的支持包（JSON）到指定路径")
+	doctorCmd.Flags().DurationVar(&doctorTimeout, "timeout", 10*time.Second, "网络检查超时时间")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+type checkResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+type doctorReport struct {
+	Version    string        `json:"version"`
+	Commit     string        `json:"commit"`
+	Date       string        `json:"date"`
+	GoVersion  string        `json:"goVersion"`
+	Platform   string        `json:"platform"`
+	Time       time.Time     `json:"time"`
+	ConfigFile string        `json:"configFile"`
+	Profile    string        `json:"profile"`
+	BaseURL    string        `json:"baseUrl"`
+	Checks     []checkResult `json:"checks"`
+}
+
+func (r *doctorReport) add(name, status, detail string) {
+	r.Checks = append(r.Checks, checkResult{Name: name, Status: status, Detail: detail})
+}
+
+func (r *doctorReport) failed() bool {
+	for _, c := range r.Checks {
+		if c.Status == checkFail {
+			return true
+		}
+	}
+	return false
+}
+
+// runDoctor 执行所有检查，baseURL 为空时使用快站默认的接口地址
+func runDoctor(baseURL string) *doctorReport {
+	report := &doctorReport{
+		Version:    Version,
+		Commit:     Commit,
+		Date:       Date,
+		GoVersion:  runtime.Version(),
+		Platform:   runtime.GOOS + "/" + runtime.GOARCH,
+		Time:       time.Now(),
+		ConfigFile: viper.ConfigFileUsed(),
+		Profile:    profile,
+	}
+
+	if report.ConfigFile != "" {
+		report.add("配置文件", checkPass, fmt.Sprintf("%s (profile %s)", report.ConfigFile, profile))
+	} else {
+		report.add("配置文件", checkWarn, fmt.Sprintf("未找到 kuanzhan.yaml (profile %s)", profile))
+	}
+
+	creds, err := credentialProvider().Retrieve()
+	if err != nil {
+		report.add("凭证", checkFail, err.Error())
+	} else {
+		report.add("凭证", checkPass, fmt.Sprintf("app_key %s from %s, app_secret from %s", redactSecret(creds.AppKey), creds.AppKeySource, creds.AppSecretSource))
+	}
+
+	client := kuanzhan.NewClient(creds.AppKey, creds.AppSecret)
+	client.BaseURL = cmp.Or(baseURL, client.BaseURL)
+	client.SetDebug(debug)
+	report.BaseURL = client.BaseURL
+
+	checkEndpoint(report, client.BaseURL, doctorTimeout)
+
+	if err != nil {
+		report.add("签名认证", checkSkip, "缺少凭证")
+	} else {
+		checkAuth(report, client)
+	}
+	return report
+}
+
+// checkEndpoint 检查接口连通性，并使用响应的 Date 头检查本地时钟
+func checkEndpoint(report *doctorReport, baseURL string, timeout time.Duration) {
+	httpClient := &http.Client{Timeout: timeout}
+	start := time.Now()
+	resp, err := httpClient.Get(baseURL)
+	if err != nil {
+		report.add("接口连通性", checkFail, err.Error())
+		report.add("本地时钟", checkSkip, "无法获取服务器时间")
+		return
+	}
+	resp.Body.Close()
+	elapsed := time.Since(start)
+	report.add("接口连通性", checkPass, fmt.Sprintf("%s HTTP %d in %s", baseURL, resp.StatusCode, elapsed.Round(time.Millisecond)))
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		report.add("本地时钟", checkSkip, "服务器响应没有 Date 头")
+		return
+	}
+
+	skew := start.Add(elapsed / 2).Sub(serverTime)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > maxClockSkew {
+		report.add("本地时钟", checkFail, fmt.Sprintf("与服务器时间相差 %s", skew.Round(time.Second)))
+		return
+	}
+	report.add("本地时钟", checkPass, fmt.Sprintf("与服务器时间相差 %s", skew.Round(time.Second)))
+}
+
+// checkAuth 使用只读接口 GetSiteIds 检查签名认证
+func checkAuth(report *doctorReport, client *kuanzhan.Client) {
+	resp, err := client.GetSiteIds()
+	if err != nil {
+		report.add("签名认证", checkFail, "GetSiteIds: "+err.Error())
+		return
+	}
+	report.add("签名认证", checkPass, fmt.Sprintf("GetSiteIds 返回 %d 个站点", len(resp.Data.SiteIds)))
+}
+
+// writeSupportBundle 写入脱敏的支持包：诊断报告、脱敏后的配置文件和相关环境变量
+func writeSupportBundle(path string, report *doctorReport) error {
+	// 检查详情中可能包含凭证来源（如 credential_process 命令）和错误信息中的凭证，替换为长度
+	secrets := secretValues(report.ConfigFile)
+	redacted := *report
+	redacted.Checks = make([]checkResult, len(report.Checks))
+	for i, c := range report.Checks {
+		for _, secret := range secrets {
+			c.Detail = strings.ReplaceAll(c.Detail, secret, redactSecret(secret))
+		}
+		redacted.Checks[i] = c
+	}
+
+	bundle := struct {
+		Report *doctorReport     `json:"report"`
+		Config string            `json:"config,omitempty"`
+		Env    map[string]string `json:"env"`
+	}{
+		Report: &redacted,
+		Env:    make(map[string]string),
+	}
+
+	if report.ConfigFile != "" {
+		config, err := redactedConfig(report.ConfigFile)
+		if err != nil {
+			bundle.Config = "error: " + err.Error()
+		} else {
+			bundle.Config = config
+		}
+	}
+
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, "KUANZHAN_") || strings.HasPrefix(name, "KUAIZHAN_") {
+			bundle.Env[name] = redactSecret(value)
+		} else if strings.HasSuffix(name, "_PROXY") || strings.HasSuffix(name, "_proxy") {
+			if u, err := url.Parse(value); err == nil {
+				value = u.Redacted()
+			}
+			bundle.Env[name] = value
+		}
+	}
+
+	b, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0600)
+}
+
+// redactSecret 支持包会发给他人，凭证只保留长度，不保留任何字符
+func redactSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return fmt.Sprintf("<redacted %d chars>", utf8.RuneCountInString(s))
+}
+
+// secretValues 命令行参数、环境变量和配置文件中的凭证，按长度从长到短排序
+func secretValues(configFile string) []string {
+	secrets := []string{appKey, appSecret}
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, "KUANZHAN_") || strings.HasPrefix(name, "KUAIZHAN_") {
+			secrets = append(secrets, value)
+		}
+	}
+	if configFile != "" {
+		if doc, err := loadConfigNode(configFile); err == nil {
+			walkMappings(doc, func(key, value *yaml.Node) error {
+				switch key.Value {
+				case "app_key", "app_secret", "credential_process":
+					if value.Kind == yaml.ScalarNode {
+						secrets = append(secrets, value.Value)
+					}
+				}
+				return nil
+			})
+		}
+	}
+	secrets = slices.DeleteFunc(secrets, func(s string) bool { return s == "" })
+	slices.SortFunc(secrets, func(a, b string) int { return cmp.Or(len(b)-len(a), strings.Compare(a, b)) })
+	return slices.Compact(secrets)
+}
+
+// redactedConfig 读取配置文件并隐藏凭证
+func redactedConfig(path string) (string, error) {
+	doc, err := loadConfigNode(path)
+	if err != nil {
+		return "", err
+	}
+
+	walkMappings(doc, func(key, value *yaml.Node) error {
+		switch key.Value {
+		case "app_key", "app_secret", "credential_process":
+			if value.Kind == yaml.ScalarNode {
+				value.Value = redactSecret(value.Value)
+			}
+		}
+		return nil
+	})
+
+	b, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"pkg.blksails.net/kuanzhan"
+)
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *kuanzhan.Client {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	client := kuanzhan.NewClient("test_key", "test_secret")
+	client.BaseURL = srv.URL
+	return client
+}
+
+func findCheck(report *doctorReport, name string) checkResult {
+	for _, c := range report.Checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	return checkResult{}
+}
+
+func TestCheckEndpoint(t *testing.T) {
+	skew := time.Duration(0)
+	client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(skew).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	report := &doctorReport{}
+	checkEndpoint(report, client.BaseURL, time.Second)
+	if c := findCheck(report, "接口连通性"); c.Status != checkPass {
+		t.Errorf("reachability: %+v", c)
+	}
+	if c := findCheck(report, "本地时钟"); c.Status != checkPass {
+		t.Errorf("clock: %+v", c)
+	}
+
+	skew = time.Hour
+	report = &doctorReport{}
+	checkEndpoint(report, client.BaseURL, time.Second)
+	if c := findCheck(report, "本地时钟"); c.Status != checkFail {
+		t.Errorf("expected clock failure: %+v", c)
+	}
+
+	report = &doctorReport{}
+	checkEndpoint(report, "http://127.0.0.1:1", time.Second)
+	if !report.failed() {
+		t.Error("expected unreachable endpoint to fail")
+	}
+}
+
+func TestCheckAuth(t *testing.T) {
+	client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tbk/getSiteIds" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"code":200,"msg":"ok","data":{"siteIds":[1,2,3]}}`))
+	})
+
+	report := &doctorReport{}
+	checkAuth(report, client)
+	if c := findCheck(report, "签名认证"); c.Status != checkPass || !strings.Contains(c.Detail, "3") {
+		t.Errorf("auth: %+v", c)
+	}
+
+	client = newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":401,"msg":"签名错误"}`))
+	})
+	report = &doctorReport{}
+	checkAuth(report, client)
+	if c := findCheck(report, "签名认证"); c.Status != checkFail || !strings.Contains(c.Detail, "签名错误") {
+		t.Errorf("auth failure: %+v", c)
+	}
+}
+
+func TestRedactedConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kuanzhan.yaml")
+	if err := os.WriteFile(path, []byte(testConfigYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := redactedConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"top_secret", "prod_key"} {
+		if strings.Contains(config, secret) {
+			t.Errorf("redacted config contains %s:\n%s", secret, config)
+		}
+	}
+}
+
+func TestWriteSupportBundle(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("credential_process test uses sh")
+	}
+	oldProfile, oldConfig := profile, viper.ConfigFileUsed()
+	t.Cleanup(func() { profile = oldProfile; viper.SetConfigFile(oldConfig) })
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "hv-get-93jdks")
+	err := os.WriteFile(script, []byte("#!/bin/sh\necho '{\"app_key\":\"Pk8Wd2Qm5Zr7\",\"app_secret\":\"Ns3Bv6Tc9Xe1\"}'\n"), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(dir, "kuanzhan.yaml")
+	err = os.WriteFile(config, []byte("app_key: AKEY12345678\napp_secret: Xq7Lm9Zp2Wv4k\nprofiles:\n  production:\n    credential_process: "+script+"\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	viper.SetConfigFile(config)
+	profile = "production"
+	t.Setenv("KUANZHAN_APP_KEY", "")
+	t.Setenv("KUANZHAN_APP_SECRET", "")
+	t.Setenv("KUANZHAN_PASSPHRASE", "Gf6Hj3Kl0")
+	t.Setenv("KUANZHAN_SESSION", "Rt5Yu8Io1Pa")
+
+	client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":200,"msg":"ok","data":{"siteIds":[1]}}`))
+	})
+	report := runDoctor(client.BaseURL)
+	if c := findCheck(report, "凭证"); c.Status != checkPass || !strings.Contains(c.Detail, script) {
+		t.Fatalf("credential check: %+v", c)
+	}
+
+	path := filepath.Join(dir, "bundle.json")
+	if err := writeSupportBundle(path, report); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := string(b)
+	for _, secret := range []string{"AKEY12345678", "Xq7Lm9Zp2Wv4k", "hv-get-93jdks", "Pk8Wd2Qm5Zr7", "Ns3Bv6Tc9Xe1", "Gf6Hj3Kl0", "Rt5Yu8Io1Pa"} {
+		for i := 0; i+4 <= len(secret); i++ {
+			if part := secret[i : i+4]; strings.Contains(bundle, part) {
+				t.Errorf("bundle contains %q of %s:\n%s", part, secret, bundle)
+			}
+		}
+	}
+	if !strings.Contains(bundle, "redacted 9 chars") {
+		t.Errorf("passphrase not redacted:\n%s", bundle)
+	}
+}
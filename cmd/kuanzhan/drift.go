@@ -0,0 +1,265 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"pkg.blksails.net/kuanzhan"
+)
+
+// 漂移类型
+const (
+	driftSiteMissing = "site-missing"    // 站点已不存在
+	driftSiteRenamed = "site-renamed"    // 站点名称被修改
+	driftDomain      = "domain-changed"  // 站点域名被修改
+	driftPackage     = "package-expired" // 套餐已过期
+	driftPageMissing = "page-missing"    // 页面已不存在
+	driftPageRenamed = "page-renamed"    // 页面名称被修改
+)
+
+// driftExitCode 存在未修复的漂移时的退出码，与错误（1）区分
+const driftExitCode = 2
+
+var (
+	driftFix         bool // 自动修复漂移
+	driftConcurrency int  // 并发获取站点信息的数量
+)
+
+var errCannotFix = errors.New("cannot be fixed automatically")
+
+// driftRow 记录的状态与账号当前状态的一处差异
+type driftRow struct {
+	SiteID int    `json:"siteId,omitempty" yaml:"siteId,omitempty"`
+	PageID int    `json:"pageId,omitempty" yaml:"pageId,omitempty"`
+	Name   string `json:"name,omitempty" yaml:"name,omitempty"`
+	Kind   string `json:"kind" yaml:"kind"`
+	Detail string `json:"detail,omitempty" yaml:"detail,omitempty"`
+	Fixed  bool   `json:"fixed" yaml:"fixed"`
+
+	resource *stateResource // 来自状态文件
+	action   *planAction    // 来自清单
+}
+
+var driftColumns = []outputColumn{
+	{"站点ID", "siteId"},
+	{"页面ID", "pageId"},
+	{"名称", "name"},
+	{"差异", "kind"},
+	{"详情", "detail"},
+}
+
+var driftFixedColumn = outputColumn{"已修复", "fixed"}
+
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "检测账号与记录状态的差异",
+	Long: `比较状态文件（或 -f 指定的期望状态清单）与账号中的站点和页面，报告控制台中修改的站点名称、域名、页面名称，删除的站点和页面，以及过期的套餐。
+状态文件中只检查当前 profile 的资源，--campaign 只检查指定活动。
+退出码：0 没有差异，1 执行出错，2 存在未修复的差异，可用于定时任务告警。
+--fix 将站点名称、域名、页面名称改回记录的值并为过期站点开通套餐；使用清单时执行 apply 的全部操作。已删除的站点和页面无法从状态文件恢复。`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client := newClient()
+
+		var (
+			rows   []*driftRow
+			failed int
+			fix    func(row *driftRow) error
+		)
+		if manifestFile != "" {
+			m, actions := loadPlan(client)
+			rows = manifestDrift(actions)
+			if driftFix {
+				inv, err := openInventory(inventoryPath())
+				if err != nil {
+					log.Fatal(err)
+				}
+				defer inv.Close()
+				a := newApplier(client, inv, m)
+				fix = func(row *driftRow) error { return a.apply(row.action) }
+			}
+		} else {
+			rows, failed = stateDrift(context.Background(), client, driftConcurrency)
+			fix = func(row *driftRow) error { return fixDrift(client, row) }
+		}
+
+		if driftFix {
+			for _, row := range rows {
+				if err := fix(row); err != nil {
+					log.Printf("fix %s %d: %v", row.Kind, cmp.Or(row.PageID, row.SiteID), err)
+					continue
+				}
+				row.Fixed = true
+			}
+		}
+
+		if len(rows) == 0 {
+			log.Println("no drift")
+		} else {
+			columns := driftColumns
+			if driftFix {
+				columns = append(slices.Clone(columns), driftFixedColumn)
+			}
+			if err := renderOutput(os.Stdout, columns, rows); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		if failed > 0 {
+			os.Exit(1)
+		}
+		if slices.ContainsFunc(rows, func(row *driftRow) bool { return !row.Fixed }) {
+			os.Exit(driftExitCode)
+		}
+	},
+}
+
+func init() {
+	driftCmd.Flags().StringVarP(&manifestFile, "file", "f", "", "与期望状态清单比较，默认与状态文件比较")
+	driftCmd.Flags().BoolVar(&driftFix, "fix", false, "自动修复差异")
+	driftCmd.Flags().IntVar(&driftConcurrency, "concurrency", 10, "并发获取站点信息的数量")
+	driftCmd.Flags().StringVarP(&businessType, "business-type", "b", "SITE_EXCLUSIVE_YEAR", "修复过期套餐时使用的套餐类型（状态文件中没有记录套餐时）")
+	rootCmd.AddCommand(driftCmd)
+}
+
+// stateDrift 比较状态文件中当前 profile 的资源与账号，返回差异和获取失败的站点数
+func stateDrift(ctx context.Context, client *kuanzhan.Client, concurrency int) ([]*driftRow, int) {
+	s, err := loadState(statePath())
+	if err != nil {
+		log.Fatal(err)
+	}
+	var resources []*stateResource
+	for _, r := range filterResources(s.Resources, campaign, "") {
+		if r.Profile == profile {
+			resources = append(resources, r)
+		}
+	}
+	if len(resources) == 0 {
+		log.Printf("no resources of profile %s in %s", profile, statePath())
+		return nil, 0
+	}
+
+	resp, err := client.GetSiteIds()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var tracked []int
+	for _, r := range resources {
+		if slices.Contains(resp.Data.SiteIds, r.SiteID) && !slices.Contains(tracked, r.SiteID) {
+			tracked = append(tracked, r.SiteID)
+		}
+	}
+
+	sites := fetchSiteRecords(ctx, client, profile, tracked, concurrency, true)
+	var failed int
+	for _, rec := range sites {
+		if rec.Error != "" {
+			log.Printf("site %d: %s", rec.SiteID, rec.Error)
+			failed++
+		}
+	}
+	return detectDrift(resources, resp.Data.SiteIds, sites), failed
+}
+
+// detectDrift 比较记录的资源与账号中的站点，获取失败的站点不比较
+func detectDrift(resources []*stateResource, siteIds []int, sites []*siteRecord) []*driftRow {
+	records := make(map[int]*siteRecord, len(sites))
+	for _, rec := range sites {
+		records[rec.SiteID] = rec
+	}
+
+	rows := []*driftRow{}
+	for _, r := range resources {
+		add := func(kind, detail string) {
+			rows = append(rows, &driftRow{SiteID: r.SiteID, PageID: r.PageID, Name: r.Name, Kind: kind, Detail: detail, resource: r})
+		}
+
+		if !slices.Contains(siteIds, r.SiteID) {
+			if r.Type == resourceSite {
+				add(driftSiteMissing, "")
+			} else {
+				add(driftPageMissing, fmt.Sprintf("site %d not found", r.SiteID))
+			}
+			continue
+		}
+		rec := records[r.SiteID]
+		if rec == nil || rec.Error != "" {
+			continue
+		}
+
+		if r.Type == resourceSite {
+			if r.Name != "" && rec.SiteName != r.Name {
+				add(driftSiteRenamed, rec.SiteName+" → "+r.Name)
+			}
+			if domain := normalizeDomain(r.Domain); domain != "" && normalizeDomain(rec.SiteDomain) != domain {
+				add(driftDomain, normalizeDomain(rec.SiteDomain)+" → "+domain)
+			}
+			if rec.PackageRemainingDays <= 0 {
+				add(driftPackage, rec.PackageName)
+			}
+			continue
+		}
+
+		i := slices.IndexFunc(rec.Pages, func(p pageRecord) bool { return p.PageID == r.PageID })
+		if i < 0 {
+			add(driftPageMissing, "")
+			continue
+		}
+		if title := rec.Pages[i].Title; r.Name != "" && title != r.Name {
+			add(driftPageRenamed, title+" → "+r.Name)
+		}
+	}
+	return rows
+}
+
+// fixDrift 将账号改回状态文件中记录的值
+func fixDrift(client *kuanzhan.Client, row *driftRow) error {
+	r := row.resource
+	switch row.Kind {
+	case driftSiteRenamed:
+		_, err := client.UpdateSiteInfo(int64(r.SiteID), r.Name)
+		return err
+	case driftDomain:
+		_, err := client.ChangeDomain(int64(r.SiteID), normalizeDomain(r.Domain), true)
+		return err
+	case driftPackage:
+		pkg := r.Package
+		if pkg == "" {
+			pkg = businessType
+		}
+		if _, err := client.OpenBusinessPackage(pkg, int64(r.SiteID), "", ""); err != nil {
+			return err
+		}
+		recordChange(resourceSite, []int{r.SiteID}, func(r *stateResource) { r.Package = pkg })
+		return nil
+	case driftPageRenamed:
+		_, err := client.UpdatePageName(r.PageID, r.Name)
+		return err
+	}
+	return errCannotFix
+}
+
+// manifestDrift 清单的每个计划操作为一处差异，修复时执行该操作
+func manifestDrift(actions []*planAction) []*driftRow {
+	rows := make([]*driftRow, 0, len(actions))
+	for _, action := range actions {
+		name := action.Site
+		if action.Page != "" {
+			name += "/" + action.Page
+		}
+		rows = append(rows, &driftRow{
+			SiteID: action.SiteID,
+			PageID: action.PageID,
+			Name:   name,
+			Kind:   action.Action,
+			Detail: action.Detail,
+			action: action,
+		})
+	}
+	return rows
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestDrift(t *testing.T) {
+	oldState, oldInventory, oldProfile := stateFilePath, inventoryFile, profile
+	t.Cleanup(func() { stateFilePath, inventoryFile, profile = oldState, oldInventory, oldProfile })
+	dir := t.TempDir()
+	stateFilePath = filepath.Join(dir, "state.json")
+	inventoryFile = filepath.Join(dir, "inventory.db")
+	profile = "default"
+
+	fake := &fakeKuanzhan{sites: map[int]*fakeSite{
+		1: {Name: "a2", Domain: "https://b.shop", RemainingDays: 0, Pages: map[int]string{10: "改名", 11: "活动"}},
+		2: {Name: "b", Domain: "b.kuaizhan.com", RemainingDays: 100},
+	}}
+	client := newTestServer(t, fake.ServeHTTP)
+
+	site := newResource(resourceSite, "create-site", 1, 0, "a", "")
+	site.Domain = "a.shop"
+	site.Package = "SITE_EXCLUSIVE_YEAR"
+	recordResources(
+		site,
+		newResource(resourcePage, "upload", 1, 10, "首页", ""),
+		newResource(resourcePage, "upload", 1, 11, "活动", ""),
+		newResource(resourcePage, "upload", 1, 12, "已删除", ""),
+		newResource(resourceSite, "create-site", 2, 0, "b", ""),
+		newResource(resourceSite, "create-site", 3, 0, "c", ""),
+	)
+
+	rows, failed := stateDrift(context.Background(), client, 2)
+	if failed != 0 {
+		t.Fatalf("failed = %d", failed)
+	}
+	want := []struct {
+		siteId, pageId int
+		kind           string
+	}{
+		{1, 0, driftSiteRenamed},
+		{1, 0, driftDomain},
+		{1, 0, driftPackage},
+		{1, 10, driftPageRenamed},
+		{1, 12, driftPageMissing},
+		{3, 0, driftSiteMissing},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("rows: %+v", rows)
+	}
+	for i, w := range want {
+		if rows[i].SiteID != w.siteId || rows[i].PageID != w.pageId || rows[i].Kind != w.kind {
+			t.Errorf("rows[%d] = %+v, want %+v", i, rows[i], w)
+		}
+	}
+	if rows[1].Detail != "b.shop → a.shop" {
+		t.Errorf("domain detail = %q", rows[1].Detail)
+	}
+
+	for _, row := range rows {
+		err := fixDrift(client, row)
+		if cannot := row.Kind == driftSiteMissing || row.Kind == driftPageMissing; cannot != (err == errCannotFix) {
+			t.Errorf("fix %s: %v", row.Kind, err)
+		}
+	}
+	if s := fake.sites[1]; s.Name != "a" || s.Domain != "a.shop" || s.Package != "SITE_EXCLUSIVE_YEAR" || s.Pages[10] != "首页" {
+		t.Errorf("site after fix: %+v", s)
+	}
+
+	rows, _ = stateDrift(context.Background(), client, 2)
+	if len(rows) != 2 {
+		t.Errorf("expected only missing resources after fix: %+v", rows)
+	}
+}
+
+func TestDriftDomainLabel(t *testing.T) {
+	oldState, oldInventory, oldProfile := stateFilePath, inventoryFile, profile
+	t.Cleanup(func() { stateFilePath, inventoryFile, profile = oldState, oldInventory, oldProfile })
+	dir := t.TempDir()
+	stateFilePath = filepath.Join(dir, "state.json")
+	inventoryFile = filepath.Join(dir, "inventory.db")
+	profile = "default"
+
+	// GetSiteInfo 返回完整域名，change-domain 记录二级域名前缀
+	fake := &fakeKuanzhan{sites: map[int]*fakeSite{
+		1: {Name: "a", Domain: "https://shop123.kuaizhan.com/", RemainingDays: 100},
+		2: {Name: "b", Domain: "https://other.kuaizhan.com", RemainingDays: 100},
+	}}
+	client := newTestServer(t, fake.ServeHTTP)
+
+	recordResources(
+		newResource(resourceSite, "create-site", 1, 0, "a", ""),
+		newResource(resourceSite, "create-site", 2, 0, "b", ""),
+	)
+	recordChange(resourceSite, []int{1}, func(r *stateResource) { r.Domain = "shop123" })
+	recordChange(resourceSite, []int{2}, func(r *stateResource) { r.Domain = "b456.kuaizhan.com" })
+
+	rows, failed := stateDrift(context.Background(), client, 2)
+	if failed != 0 || len(rows) != 1 {
+		t.Fatalf("rows: %+v, failed %d", rows, failed)
+	}
+	if rows[0].SiteID != 2 || rows[0].Kind != driftDomain || rows[0].Detail != "other → b456" {
+		t.Errorf("row = %+v", rows[0])
+	}
+	if err := fixDrift(client, rows[0]); err != nil || fake.sites[2].Domain != "b456" {
+		t.Errorf("fix: %v, domain %q", err, fake.sites[2].Domain)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// userAgents --user-agent 的预设
+var userAgents = map[string]string{
+	"mobile":  "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+	"wechat":  "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 MicroMessenger/8.0.47(0x18002f2c) NetType/WIFI Language/zh_CN",
+	"desktop": "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+}
+
+// fetchOptions 下载源页面的 HTTP 选项
+type fetchOptions struct {
+	UserAgent    string        // User-Agent 或预设名称
+	Headers      []string      // 额外的请求头 "Name: value"
+	Cookies      []string      // 发送给源页面所在主机的 cookie "name=value"
+	CookieFile   string        // Netscape 格式的 cookies.txt
+	Timeout      time.Duration // 单个请求的超时时间
+	MaxBodySize  int64         // 源页面的最大字节数
+	Proxy        string        // 代理地址，为空时使用 HTTP_PROXY/HTTPS_PROXY 环境变量
+	MaxRedirects int           // 最多跟随的重定向次数，0 表示不跟随
+}
+
+var fetchOpts = fetchOptions{
+	UserAgent:    "mobile",
+	Timeout:      30 * time.Second,
+	MaxBodySize:  10 << 20,
+	MaxRedirects: 10,
+}
+
+// addFetchFlags 注册下载源页面的 HTTP 选项
+func addFetchFlags(cmd *cobra.Command) {
+	pflags := cmd.PersistentFlags()
+	pflags.StringVar(&fetchOpts.UserAgent, "user-agent", fetchOpts.UserAgent, "下载源页面的 User-Agent，预设: mobile|wechat|desktop，或完整的 User-Agent")
+	pflags.StringArrayVar(&fetchOpts.Headers, "header", nil, "下载源页面的请求头 'Name: value'，可以指定多次")
+	pflags.StringArrayVar(&fetchOpts.Cookies, "cookie", nil, "发送给源页面所在主机的 cookie 'name=value'，可以指定多次")
+	pflags.StringVar(&fetchOpts.CookieFile, "cookie-file", "", "Netscape 格式的 cookies.txt")
+	pflags.DurationVar(&fetchOpts.Timeout, "timeout", fetchOpts.Timeout, "下载超时时间")
+	pflags.Int64Var(&fetchOpts.MaxBodySize, "max-body-size", fetchOpts.MaxBodySize, "源页面的最大字节数")
+	pflags.StringVar(&fetchOpts.Proxy, "proxy", "", "代理地址，默认使用 HTTP_PROXY/HTTPS_PROXY 环境变量")
+	pflags.IntVar(&fetchOpts.MaxRedirects, "max-redirects", fetchOpts.MaxRedirects, "最多跟随的重定向次数，0 表示不跟随")
+}
+
+// fetcher 下载源页面和资源
+type fetcher struct {
+	client  *http.Client
+	header  http.Header
+	maxBody int64
+}
+
+// fetchResult 下载结果
+type fetchResult struct {
+	Body        []byte
+	URL         *url.URL // 重定向后的地址
+	ContentType string
+}
+
+// newFetcher 按选项创建 fetcher，--cookie 设置到 pageURL 所在主机
+func newFetcher(opts fetchOptions, pageURL string) (*fetcher, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	if opts.CookieFile != "" {
+		if err := loadCookieFile(jar, opts.CookieFile); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.Cookies) > 0 {
+		u, err := url.Parse(pageURL)
+		if err != nil {
+			return nil, err
+		}
+		var cookies []*http.Cookie
+		for _, c := range opts.Cookies {
+			name, value, ok := strings.Cut(c, "=")
+			if !ok || name == "" {
+				return nil, fmt.Errorf("invalid --cookie %q, must be name=value", c)
+			}
+			cookies = append(cookies, &http.Cookie{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+		}
+		jar.SetCookies(u, cookies)
+	}
+
+	header := make(http.Header)
+	if ua := opts.UserAgent; ua != "" {
+		if preset, ok := userAgents[ua]; ok {
+			ua = preset
+		}
+		header.Set("User-Agent", ua)
+	}
+	for _, h := range opts.Headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid --header %q, must be 'Name: value'", h)
+		}
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.Proxy != "" {
+		proxy, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	maxRedirects := opts.MaxRedirects
+	client := &http.Client{
+		Transport: transport,
+		Jar:       jar,
+		Timeout:   opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if maxRedirects == 0 {
+				return http.ErrUseLastResponse
+			}
+			if len(via) > maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			// net/http 在重定向时复制请求头，跨主机时去掉 Authorization 和 Cookie
+			return nil
+		},
+	}
+	return &fetcher{client: client, header: header, maxBody: opts.MaxBodySize}, nil
+}
+
+// get 下载地址，非 2xx 响应或超过 limit 字节时返回错误，limit 为 0 时使用 MaxBodySize
+func (f *fetcher) get(rawURL string, limit int64) (*fetchResult, error) {
+	if limit == 0 {
+		limit = f.maxBody
+	}
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range f.header {
+		req.Header[k] = v
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		if location := resp.Header.Get("Location"); location != "" {
+			return nil, fmt.Errorf("GET %s: %s, redirect to %s not followed", rawURL, resp.Status, location)
+		}
+		return nil, fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	if limit > 0 && resp.ContentLength > limit {
+		return nil, fmt.Errorf("GET %s: larger than %d bytes", rawURL, limit)
+	}
+	r := io.Reader(resp.Body)
+	if limit > 0 {
+		r = io.LimitReader(resp.Body, limit+1)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", rawURL, err)
+	}
+	if limit > 0 && int64(len(body)) > limit {
+		return nil, fmt.Errorf("GET %s: larger than %d bytes", rawURL, limit)
+	}
+	return &fetchResult{Body: body, URL: resp.Request.URL, ContentType: resp.Header.Get("Content-Type")}, nil
+}
+
+// loadCookieFile 读取 Netscape 格式的 cookies.txt
+// 每行为 domain, include subdomains, path, secure, expires, name, value，以 TAB 分隔
+func loadCookieFile(jar http.CookieJar, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimPrefix(scanner.Text(), "#HttpOnly_")
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return fmt.Errorf("%s:%d: expected 7 tab-separated fields", path, line)
+		}
+		domain, cookiePath, secure := fields[0], fields[2], strings.EqualFold(fields[3], "TRUE")
+		cookie := &http.Cookie{Name: fields[5], Value: fields[6], Path: cookiePath, Secure: secure}
+		if strings.HasPrefix(domain, ".") || strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = domain
+		}
+		if expires, err := strconv.ParseInt(fields[4], 10, 64); err == nil && expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: strings.TrimPrefix(domain, "."), Path: cookiePath}, []*http.Cookie{cookie})
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetcher(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			var cookies []string
+			for _, c := range r.Cookies() {
+				cookies = append(cookies, c.Name+"="+c.Value)
+			}
+			w.Write([]byte(r.UserAgent() + "|" + r.Header.Get("Referer") + "|" + strings.Join(cookies, ";")))
+		case "/moved":
+			http.Redirect(w, r, "/echo", http.StatusFound)
+		case "/big":
+			w.Write(make([]byte, 2048))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cookieFile := filepath.Join(t.TempDir(), "cookies.txt")
+	host := strings.TrimPrefix(srv.URL, "http://")
+	host = host[:strings.LastIndex(host, ":")]
+	err := os.WriteFile(cookieFile, []byte("# Netscape HTTP Cookie File\n"+host+"\tFALSE\t/\tFALSE\t0\tsession\tabc\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := fetchOptions{
+		UserAgent:    "wechat",
+		Headers:      []string{"Referer: https://mp.weixin.qq.com/"},
+		Cookies:      []string{"uid=1"},
+		CookieFile:   cookieFile,
+		Timeout:      5 * time.Second,
+		MaxBodySize:  1024,
+		MaxRedirects: 10,
+	}
+	f, err := newFetcher(opts, srv.URL+"/echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := f.get(srv.URL+"/moved", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(string(res.Body), "|")
+	if !strings.Contains(parts[0], "MicroMessenger") || parts[1] != "https://mp.weixin.qq.com/" {
+		t.Errorf("request headers: %q", res.Body)
+	}
+	if !strings.Contains(parts[2], "uid=1") || !strings.Contains(parts[2], "session=abc") {
+		t.Errorf("cookies: %q", parts[2])
+	}
+	if res.URL.Path != "/echo" {
+		t.Errorf("final url = %s", res.URL)
+	}
+
+	if _, err := f.get(srv.URL+"/missing", 0); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected 404 error, got %v", err)
+	}
+	if _, err := f.get(srv.URL+"/big", 0); err == nil {
+		t.Error("expected error for body larger than MaxBodySize")
+	}
+
+	opts.MaxRedirects = 0
+	f, err = newFetcher(opts, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.get(srv.URL+"/moved", 0); err == nil || !strings.Contains(err.Error(), "not followed") {
+		t.Errorf("expected redirect error, got %v", err)
+	}
+
+	if _, err := newFetcher(fetchOptions{Headers: []string{"bad"}}, srv.URL); err == nil {
+		t.Error("expected error for invalid header")
+	}
+}
+
+func TestFetcherCrossHostRedirect(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization") + "|" + r.Header.Get("Cookie") + "|" + r.Header.Get("X-Trace")))
+	}))
+	defer other.Close()
+	// 同一个服务器通过 localhost 访问，主机名不同
+	otherURL := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same":
+			http.Redirect(w, r, "/echo", http.StatusFound)
+		case "/echo":
+			w.Write([]byte(r.Header.Get("Authorization") + "|" + r.Header.Get("Cookie") + "|" + r.Header.Get("X-Trace")))
+		default:
+			http.Redirect(w, r, otherURL+"/", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	opts := fetchOptions{
+		Headers:      []string{"Authorization: Bearer t0ken", "Cookie: sid=1", "X-Trace: 7"},
+		Timeout:      5 * time.Second,
+		MaxBodySize:  1024,
+		MaxRedirects: 10,
+	}
+	f, err := newFetcher(opts, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := f.get(srv.URL+"/same", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res.Body) != "Bearer t0ken|sid=1|7" {
+		t.Errorf("same host redirect: %q", res.Body)
+	}
+
+	res, err = f.get(srv.URL+"/away", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res.Body) != "||7" {
+		t.Errorf("cross host redirect should drop Authorization and Cookie: %q", res.Body)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// siteField 可用于 --sort-by 和 --columns 的字段
+type siteField struct {
+	column outputColumn
+	value  func(r siteRow) any
+}
+
+var siteFields = []siteField{
+	{accountColumn, func(r siteRow) any { return r.Account }},
+	{outputColumn{"站点ID", "siteId"}, func(r siteRow) any { return r.SiteID }},
+	{outputColumn{"站点名称", "siteName"}, func(r siteRow) any { return r.SiteName }},
+	{outputColumn{"站点URL", "siteDomain"}, func(r siteRow) any { return r.SiteDomain }},
+	{outputColumn{"套餐类型", "packageName"}, func(r siteRow) any { return r.PackageName }},
+	{outputColumn{"站点状态", "siteStatus"}, func(r siteRow) any { return r.SiteStatus }},
+	{remainingDaysColumn, func(r siteRow) any { return r.PackageRemainingDays }},
+	{outputColumn{"页面ID", "pageId"}, func(r siteRow) any { return r.PageID }},
+	{outputColumn{"页面名称", "pageName"}, func(r siteRow) any { return r.PageName }},
+	{outputColumn{"页面URL", "pageUrl"}, func(r siteRow) any { return r.PageURL }},
+	{errorColumn, func(r siteRow) any { return r.Error }},
+	{fetchedAtColumn, func(r siteRow) any { return r.FetchedAt }},
+}
+
+// siteFieldNames 所有字段名
+func siteFieldNames() []string {
+	names := make([]string, len(siteFields))
+	for i, f := range siteFields {
+		names[i] = f.column.Field
+	}
+	return names
+}
+
+func siteFieldIndex(name string) int {
+	return slices.IndexFunc(siteFields, func(f siteField) bool {
+		return strings.EqualFold(f.column.Field, name)
+	})
+}
+
+// selectColumns 按 --columns 的字段名选择输出列
+func selectColumns(names []string) ([]outputColumn, error) {
+	columns := make([]outputColumn, 0, len(names))
+	for _, name := range names {
+		i := siteFieldIndex(strings.TrimSpace(name))
+		if i < 0 {
+			return nil, fmt.Errorf("unknown column %q, available: %s", name, strings.Join(siteFieldNames(), ","))
+		}
+		columns = append(columns, siteFields[i].column)
+	}
+	return columns, nil
+}
+
+// sortRows 按 --sort-by 的字段排序，字段名前加 - 表示降序，排序是稳定的
+func sortRows(rows []siteRow, keys []string) error {
+	type sortKey struct {
+		value func(r siteRow) any
+		desc  bool
+	}
+	sortKeys := make([]sortKey, 0, len(keys))
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		desc := strings.HasPrefix(key, "-")
+		i := siteFieldIndex(strings.TrimPrefix(key, "-"))
+		if i < 0 {
+			return fmt.Errorf("unknown sort field %q, available: %s", key, strings.Join(siteFieldNames(), ","))
+		}
+		sortKeys = append(sortKeys, sortKey{siteFields[i].value, desc})
+	}
+
+	slices.SortStableFunc(rows, func(a, b siteRow) int {
+		for _, k := range sortKeys {
+			c := compareField(k.value(a), k.value(b))
+			if k.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	return nil
+}
+
+func compareField(a, b any) int {
+	switch a := a.(type) {
+	case int:
+		return cmp.Compare(a, b.(int))
+	case string:
+		return cmp.Compare(a, b.(string))
+	}
+	return 0
+}
+
+// siteFilter list 的过滤条件，多个条件同时满足才保留
+type siteFilter struct {
+	Status         []string       // 站点状态，不区分大小写
+	Package        []string       // 套餐类型，不区分大小写
+	Name           *regexp.Regexp // 站点名称正则
+	Domain         string         // 站点域名 glob
+	ExpiringWithin int            // 套餐剩余天数不超过该值，-1 表示不过滤
+}
+
+// newSiteFilter 解析过滤参数
+func newSiteFilter(status, pkg []string, name, domain, expiringWithin string) (*siteFilter, error) {
+	f := &siteFilter{Status: status, Package: pkg, Domain: domain, ExpiringWithin: -1}
+	if name != "" {
+		re, err := regexp.Compile(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --name: %w", err)
+		}
+		f.Name = re
+	}
+	if domain != "" {
+		if _, err := path.Match(domain, ""); err != nil {
+			return nil, fmt.Errorf("invalid --domain: %w", err)
+		}
+	}
+	if expiringWithin != "" {
+		days, err := parseDays(expiringWithin)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --expiring-within: %w", err)
+		}
+		f.ExpiringWithin = days
+	}
+	return f, nil
+}
+
+// parseDays 解析天数，支持 30、30d 以及 Go 的 duration 格式（如 720h）
+func parseDays(s string) (int, error) {
+	if n, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil {
+		if n < 0 {
+			return 0, fmt.Errorf("negative days %q", s)
+		}
+		return n, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration %q", s)
+	}
+	return int((d + 24*time.Hour - 1) / (24 * time.Hour)), nil
+}
+
+// match 记录是否满足过滤条件，获取失败的记录总是保留，避免隐藏错误
+func (f *siteFilter) match(r siteRow) bool {
+	if r.Error != "" && r.SiteName == "" {
+		return true
+	}
+	if len(f.Status) > 0 && !containsFold(f.Status, r.SiteStatus) {
+		return false
+	}
+	if len(f.Package) > 0 && !containsFold(f.Package, r.PackageName) {
+		return false
+	}
+	if f.Name != nil && !f.Name.MatchString(r.SiteName) {
+		return false
+	}
+	if f.Domain != "" {
+		if ok, _ := path.Match(f.Domain, siteHost(r.SiteDomain)); !ok {
+			return false
+		}
+	}
+	if f.ExpiringWithin >= 0 && r.PackageRemainingDays > f.ExpiringWithin {
+		return false
+	}
+	return true
+}
+
+// siteHost 站点域名的主机部分，GetSiteInfo 返回的域名可能带有协议和末尾的 /
+func siteHost(domain string) string {
+	domain = strings.TrimPrefix(strings.TrimPrefix(domain, "https://"), "http://")
+	host, _, _ := strings.Cut(domain, "/")
+	return host
+}
+
+// apply 返回满足过滤条件的记录
+func (f *siteFilter) apply(rows []siteRow) []siteRow {
+	return slices.DeleteFunc(rows, func(r siteRow) bool { return !f.match(r) })
+}
+
+func containsFold(values []string, s string) bool {
+	return slices.ContainsFunc(values, func(v string) bool { return strings.EqualFold(v, s) })
+}
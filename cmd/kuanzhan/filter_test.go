@@ -0,0 +1,108 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+var filterRows = []siteRow{
+	{SiteID: 1, SiteName: "shop-a", SiteDomain: "a.kuaizhan.com", SiteStatus: "ONLINE", PackageName: "SITE_EXCLUSIVE_YEAR", PackageRemainingDays: 200},
+	{SiteID: 2, SiteName: "shop-b", SiteDomain: "b.example.shop", SiteStatus: "OFFLINE", PackageName: "FREE", PackageRemainingDays: 10},
+	{SiteID: 3, SiteName: "blog", SiteDomain: "c.kuaizhan.com", SiteStatus: "online", PackageName: "SITE_EXCLUSIVE_YEAR", PackageRemainingDays: 30},
+	{SiteID: 4, Error: "GetSiteInfo: boom"},
+}
+
+func siteIdsOf(rows []siteRow) []int {
+	ids := []int{}
+	for _, r := range rows {
+		ids = append(ids, r.SiteID)
+	}
+	return ids
+}
+
+func TestSiteFilter(t *testing.T) {
+	tests := []struct {
+		name           string
+		status, pkg    []string
+		re, domain     string
+		expiringWithin string
+		want           []int
+	}{
+		{name: "none", want: []int{1, 2, 3, 4}},
+		{name: "status", status: []string{"ONLINE"}, want: []int{1, 3, 4}},
+		{name: "package", pkg: []string{"free"}, want: []int{2, 4}},
+		{name: "name", re: "^shop-", want: []int{1, 2, 4}},
+		{name: "domain", domain: "*.kuaizhan.com", want: []int{1, 3, 4}},
+		{name: "expiring", expiringWithin: "30d", want: []int{2, 3, 4}},
+		{name: "expiring hours", expiringWithin: "240h", want: []int{2, 4}},
+		{name: "combined", status: []string{"online"}, re: "shop", want: []int{1, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newSiteFilter(tt.status, tt.pkg, tt.re, tt.domain, tt.expiringWithin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := siteIdsOf(f.apply(slices.Clone(filterRows)))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// GetSiteInfo 返回的域名带有协议和末尾的 /
+	schemeRows := []siteRow{
+		{SiteID: 5, SiteDomain: "https://shop123.kuaizhan.com/"},
+		{SiteID: 6, SiteDomain: "http://d.example.shop"},
+	}
+	for domain, want := range map[string][]int{"*.kuaizhan.com": {5}, "*.shop": {6}, "shop123.kuaizhan.com": {5}} {
+		f, err := newSiteFilter(nil, nil, "", domain, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := siteIdsOf(f.apply(slices.Clone(schemeRows))); !slices.Equal(got, want) {
+			t.Errorf("--domain %s: got %v, want %v", domain, got, want)
+		}
+	}
+
+	if _, err := newSiteFilter(nil, nil, "(", "", ""); err == nil {
+		t.Error("expected invalid regex error")
+	}
+	if _, err := newSiteFilter(nil, nil, "", "", "soon"); err == nil {
+		t.Error("expected invalid --expiring-within error")
+	}
+}
+
+func TestSortRows(t *testing.T) {
+	rows := slices.Clone(filterRows)
+	if err := sortRows(rows, []string{"-packageRemainingDays"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := siteIdsOf(rows); !slices.Equal(got, []int{1, 3, 2, 4}) {
+		t.Errorf("got %v", got)
+	}
+
+	if err := sortRows(rows, []string{"packageName", "-siteId"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := siteIdsOf(rows); !slices.Equal(got, []int{4, 2, 3, 1}) {
+		t.Errorf("got %v", got)
+	}
+
+	if err := sortRows(rows, []string{"bogus"}); err == nil {
+		t.Error("expected unknown field error")
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	columns, err := selectColumns([]string{"siteId", "packageRemainingDays"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(columns) != 2 || columns[1] != remainingDaysColumn {
+		t.Errorf("got %v", columns)
+	}
+	if _, err := selectColumns([]string{"nope"}); err == nil {
+		t.Error("expected unknown column error")
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"pkg.blksails.net/kuanzhan"
+)
+
+var (
+	historyShow int // history 输出指定版本的内容
+	rollbackTo  int // rollback 的目标版本
+)
+
+// historyRow 页面的一个内容版本，版本号按上传时间从 1 开始
+type historyRow struct {
+	Version    int    `json:"version" yaml:"version"`
+	UploadedAt string `json:"uploadedAt" yaml:"uploadedAt"`
+	Hash       string `json:"hash" yaml:"hash"`
+	Size       int64  `json:"size" yaml:"size"`
+	Source     string `json:"source" yaml:"source"`
+	TaskID     string `json:"taskId,omitempty" yaml:"taskId,omitempty"`
+}
+
+var historyColumns = []outputColumn{
+	{"版本", "version"},
+	{"上传时间", "uploadedAt"},
+	{"哈希", "hash"},
+	{"大小", "size"},
+	{"来源", "source"},
+	{"任务ID", "taskId"},
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history <pageId>",
+	Short: "页面内容历史",
+	Long: `列出内容归档中页面的所有上传版本，版本号按上传时间从 1 开始，最后一个版本为页面当前内容。
+只包含通过 upload、apply、rollback 上传的内容，--show 输出指定版本的内容。`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		pageId, err := strconv.Atoi(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		inv, err := openInventory(inventoryPath())
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer inv.Close()
+
+		uploads, err := inv.Uploads(profile, pageId)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(uploads) == 0 {
+			log.Fatalf("page %d has no uploads in the content archive", pageId)
+		}
+
+		if historyShow != 0 {
+			upload, err := uploadVersion(uploads, historyShow)
+			if err != nil {
+				log.Fatal(err)
+			}
+			content, err := readContent(upload.Hash)
+			if err != nil {
+				log.Fatal(err)
+			}
+			os.Stdout.Write(content)
+			return
+		}
+
+		rows := make([]historyRow, 0, len(uploads))
+		for i, upload := range uploads {
+			row := historyRow{
+				Version:    i + 1,
+				UploadedAt: upload.UploadedAt.Local().Format(time.DateTime),
+				Hash:       upload.Hash,
+				Size:       -1,
+				Source:     upload.Source,
+				TaskID:     upload.TaskID,
+			}
+			if info, err := os.Stat(filepath.Join(contentDir(), upload.Hash)); err == nil {
+				row.Size = info.Size()
+			}
+			rows = append(rows, row)
+		}
+		if err := renderOutput(os.Stdout, historyColumns, rows); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <pageId>",
+	Short: "回滚页面内容",
+	Long: `将内容归档中页面的历史版本重新上传并发布，默认回滚到上一个版本，--to 指定版本（见 kuanzhan history）。
+回滚本身记录为一个新版本，可以再次回滚。`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		pageId, err := strconv.Atoi(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		inv, err := openInventory(inventoryPath())
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer inv.Close()
+
+		rec, err := rollbackPage(newClient(), inv, pageId, rollbackTo)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("page %d rolled back to %s", pageId, shortHash(rec.Hash))
+	},
+}
+
+func init() {
+	historyCmd.Flags().IntVar(&historyShow, "show", 0, "输出指定版本的内容")
+	rollbackCmd.Flags().IntVar(&rollbackTo, "to", 0, "目标版本，默认上一个版本")
+	rootCmd.AddCommand(historyCmd, rollbackCmd)
+}
+
+// uploadVersion 版本号对应的上传记录
+func uploadVersion(uploads []*uploadRecord, version int) (*uploadRecord, error) {
+	if version < 1 || version > len(uploads) {
+		return nil, fmt.Errorf("version %d not found, page has versions 1-%d", version, len(uploads))
+	}
+	return uploads[version-1], nil
+}
+
+// rollbackPage 重新上传并发布页面的指定版本，version 为 0 时回滚到上一个版本，返回新的上传记录
+func rollbackPage(client *kuanzhan.Client, inv *inventory, pageId, version int) (*uploadRecord, error) {
+	uploads, err := inv.Uploads(profile, pageId)
+	if err != nil {
+		return nil, err
+	}
+	if len(uploads) == 0 {
+		return nil, fmt.Errorf("page %d has no uploads in the content archive", pageId)
+	}
+	if version == 0 {
+		if len(uploads) < 2 {
+			return nil, fmt.Errorf("page %d has no previous version", pageId)
+		}
+		version = len(uploads) - 1
+	}
+	target, err := uploadVersion(uploads, version)
+	if err != nil {
+		return nil, err
+	}
+	current := uploads[len(uploads)-1]
+	if target.Hash == current.Hash {
+		return nil, fmt.Errorf("page %d: version %d is the same as the current content", pageId, version)
+	}
+	if current.SiteID == 0 {
+		return nil, fmt.Errorf("page %d: site unknown, run kuanzhan sync and upload again", pageId)
+	}
+
+	content, err := readContent(target.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := client.ModifyPageJs(current.SiteID, strconv.Itoa(pageId), string(content), false); err != nil {
+		return nil, err
+	}
+	if _, err := client.PublishPage(current.SiteID, pageId); err != nil {
+		return nil, err
+	}
+
+	rec := &uploadRecord{
+		Account:    profile,
+		SiteID:     current.SiteID,
+		PageID:     pageId,
+		Hash:       target.Hash,
+		Source:     fmt.Sprintf("rollback to version %d (%s)", version, target.Source),
+		UploadedAt: time.Now(),
+	}
+	if err := inv.PutUploads(rec); err != nil {
+		return nil, fmt.Errorf("record rollback: %w", err)
+	}
+	recordContent([]int{pageId}, target.Hash)
+	return rec, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRollbackPage(t *testing.T) {
+	oldInventory, oldProfile := inventoryFile, profile
+	t.Cleanup(func() { inventoryFile, profile = oldInventory, oldProfile })
+	inventoryFile = filepath.Join(t.TempDir(), "inventory.db")
+	profile = "default"
+
+	inv, err := openInventory(inventoryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inv.Close()
+
+	fake := &fakeKuanzhan{sites: map[int]*fakeSite{
+		1: {Name: "a", Domain: "a.kuaizhan.com", Pages: map[int]string{10: "首页"}},
+	}}
+	client := newTestServer(t, fake.ServeHTTP)
+
+	now := time.Now()
+	for i, content := range []string{"v1", "v2"} {
+		hash, err := putContent([]byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = inv.PutUploads(&uploadRecord{Account: profile, SiteID: 1, PageID: 10, Hash: hash, Source: content + ".html", UploadedAt: now.Add(time.Duration(i-2) * time.Minute)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := rollbackPage(client, inv, 10, 2); err == nil {
+		t.Error("expected error rolling back to the current version")
+	}
+	if _, err := rollbackPage(client, inv, 10, 5); err == nil {
+		t.Error("expected error for unknown version")
+	}
+
+	rec, err := rollbackPage(client, inv, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Hash != contentHash([]byte("v1")) || fake.sites[1].Contents[10] != "v1" || fake.sites[1].Published[10] != 1 {
+		t.Errorf("rollback: %+v, site %+v", rec, fake.sites[1])
+	}
+
+	uploads, err := inv.Uploads(profile, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uploads) != 3 || uploads[2].Hash != rec.Hash {
+		t.Errorf("rollback should be recorded as a new version: %+v", uploads)
+	}
+
+	// 回滚可以再次回滚
+	if rec, err = rollbackPage(client, inv, 10, 0); err != nil || fake.sites[1].Contents[10] != "v2" {
+		t.Errorf("second rollback: %+v, %v", rec, err)
+	}
+}
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// 资源处理结果
+const (
+	assetResolved = "resolved" // 相对地址解析为绝对地址
+	assetInlined  = "inlined"  // 内联为 data URI 或 <style>
+	assetSkipped  = "skipped"  // 没有内联，保留绝对地址
+)
+
+// importOptions 从源页面导入内容的选项
+type importOptions struct {
+	KeepHead      bool   // 保留 head 中的样式、脚本和 meta
+	InlineAssets  bool   // 内联小图片和样式表
+	InlineMaxSize int64  // 内联资源的最大字节数
+	Charset       string // 源页面编码，为空时自动检测
+}
+
+var (
+	importOpts  importOptions // upload、import-site 的导入选项
+	assetReport bool          // 输出资源处理报告
+)
+
+func init() {
+	addImportFlags(uploadSiteCmd)
+}
+
+// addImportFlags 注册导入源页面的选项，包括下载选项
+func addImportFlags(cmd *cobra.Command) {
+	pflags := cmd.PersistentFlags()
+	pflags.BoolVar(&importOpts.KeepHead, "keep-head", false, "保留源页面 head 中的样式表、样式、脚本和 meta，<title> 作为默认页面名称")
+	pflags.BoolVar(&importOpts.InlineAssets, "inline-assets", false, "将不超过 --inline-max-size 的图片内联为 data URI，样式表内联为 <style>")
+	pflags.Int64Var(&importOpts.InlineMaxSize, "inline-max-size", 32<<10, "内联资源的最大字节数")
+	pflags.BoolVar(&assetReport, "asset-report", false, "输出导入时改写的资源地址")
+	pflags.StringVar(&importOpts.Charset, "charset", "", "源页面编码，如 gbk、gb18030，默认根据响应头、meta 和内容检测")
+	addFetchFlags(cmd)
+}
+
+// urlAttrs 需要解析为绝对地址的属性
+var urlAttrs = []string{"src", "href", "poster", "action", "data-src", "data-original"}
+
+// cssURLPattern CSS 中的 url(...)
+var cssURLPattern = regexp.MustCompile(`url\(\s*(['"]?)([^'")]*)(['"]?)\s*\)`)
+
+// assetRewrite 导入时改写的一个资源地址
+type assetRewrite struct {
+	Tag      string `json:"tag" yaml:"tag"`
+	Attr     string `json:"attr" yaml:"attr"`
+	Original string `json:"original" yaml:"original"`
+	URL      string `json:"url" yaml:"url"`
+	Result   string `json:"result" yaml:"result"`
+	Size     int    `json:"size,omitempty" yaml:"size,omitempty"`
+	Reason   string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+var assetColumns = []outputColumn{
+	{"标签", "tag"},
+	{"属性", "attr"},
+	{"原地址", "original"},
+	{"地址", "url"},
+	{"结果", "result"},
+	{"大小", "size"},
+	{"原因", "reason"},
+}
+
+// importedPage 从源页面导入的内容
+type importedPage struct {
+	URL     string // 重定向后的地址
+	Content []byte
+	Title   string         // 源页面的 <title>
+	Assets  []assetRewrite // 改写的资源地址
+	Charset string         // 源页面编码，内容已转换为 UTF-8
+	Links   []string       // <a> 链接的绝对地址
+}
+
+// importer 导入源页面，解析相对地址并按需内联资源
+type importer struct {
+	opts    importOptions
+	base    *url.URL
+	fetcher *fetcher
+	assets  []assetRewrite
+	links   []string
+}
+
+// fetchPage 按 --user-agent、--timeout 等选项下载并导入源页面
+func fetchPage(rawURL string, opts importOptions) (*importedPage, error) {
+	f, err := newFetcher(fetchOpts, rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return loadPage(f, rawURL, opts)
+}
+
+// loadPage 使用 f 下载并导入源页面，响应不是 HTML 时返回错误
+func loadPage(f *fetcher, rawURL string, opts importOptions) (*importedPage, error) {
+	res, err := f.get(rawURL, 0)
+	if err != nil {
+		return nil, err
+	}
+	if mediaType, _, _ := mime.ParseMediaType(res.ContentType); mediaType != "" && !strings.HasPrefix(mediaType, "text/") && !strings.Contains(mediaType, "html") {
+		return nil, fmt.Errorf("GET %s: not an html page: %s", rawURL, mediaType)
+	}
+
+	content, name, err := decodeHTML(res.Body, res.ContentType, opts.Charset)
+	if err != nil {
+		return nil, err
+	}
+	page, err := importPage(bytes.NewReader(content), res.URL, opts, f)
+	if err != nil {
+		return nil, err
+	}
+	page.Charset = name
+	page.URL = res.URL.String()
+	return page, nil
+}
+
+// readLocalPage 读取本地页面文件，转换为 UTF-8
+func readLocalPage(path, charset string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content, name, err := decodeHTML(b, "", charset)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if name != "utf-8" {
+		log.Printf("%s: transcoded from %s to utf-8", path, name)
+	}
+	return content, nil
+}
+
+// metaCharsetPattern meta 中声明的编码
+var metaCharsetPattern = regexp.MustCompile(`(?i)(<meta[^>]*charset\s*=\s*["']?)([\w-]+)`)
+
+// decodeHTML 将页面内容转换为 UTF-8，返回原编码名称
+// 编码依次取 charset 参数、BOM、Content-Type、meta 声明；都没有时内容是合法 UTF-8 则为 UTF-8，否则按 GB18030 处理
+// 转换后 meta 中声明的编码改为 utf-8
+func decodeHTML(content []byte, contentType, name string) ([]byte, string, error) {
+	var enc encoding.Encoding
+	if name != "" {
+		e, err := htmlindex.Get(name)
+		if err != nil {
+			return nil, "", fmt.Errorf("unknown charset %q", name)
+		}
+		enc = e
+		name, _ = htmlindex.Name(e)
+	} else {
+		var certain bool
+		enc, name, certain = charset.DetermineEncoding(content, contentType)
+		// 没有声明编码时只检测前 1024 字节，整个内容都是有效的 UTF-8（包括纯 ASCII）时按 UTF-8 处理；
+		// 否则中文站点通常为 GBK/GB18030，而不是默认的 windows-1252
+		if !certain && name == "windows-1252" && !hasCharsetDeclaration(content, contentType) {
+			if utf8.Valid(content) {
+				name = "utf-8"
+			} else {
+				enc, name = simplifiedchinese.GB18030, "gb18030"
+			}
+		}
+	}
+
+	if name == "utf-8" {
+		return bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")), name, nil
+	}
+	decoded, err := enc.NewDecoder().Bytes(content)
+	if err != nil {
+		return nil, "", fmt.Errorf("decode %s: %w", name, err)
+	}
+	return metaCharsetPattern.ReplaceAll(decoded, []byte("${1}utf-8")), name, nil
+}
+
+// hasCharsetDeclaration Content-Type 或 meta 中是否声明了编码
+func hasCharsetDeclaration(content []byte, contentType string) bool {
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		return true
+	}
+	return metaCharsetPattern.Match(content[:min(len(content), 1024)])
+}
+
+// printAssetReport 输出资源处理报告
+func printAssetReport(page *importedPage) {
+	if len(page.Assets) == 0 && !assetReport {
+		return
+	}
+	var inlined, skipped int
+	for _, a := range page.Assets {
+		switch a.Result {
+		case assetInlined:
+			inlined++
+		case assetSkipped:
+			skipped++
+		}
+	}
+	log.Printf("assets: %d rewritten, %d inlined, %d not inlined", len(page.Assets), inlined, skipped)
+	if assetReport {
+		if err := renderOutput(os.Stdout, assetColumns, page.Assets); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// importPage 解析页面，返回 body 的内部内容，相对地址按 base 解析为绝对地址
+// KeepHead 时在内容前加上 head 中的样式表、样式、脚本和 meta，InlineAssets 时使用 f 下载资源
+func importPage(r io.Reader, base *url.URL, opts importOptions, f *fetcher) (*importedPage, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// 查找body节点
+	bodyNode := findNode(doc, atom.Body)
+	if bodyNode == nil {
+		return nil, fmt.Errorf("body node not found")
+	}
+
+	page := &importedPage{}
+	if title := findNode(doc, atom.Title); title != nil && title.FirstChild != nil {
+		page.Title = strings.TrimSpace(title.FirstChild.Data)
+	}
+
+	head := findNode(doc, atom.Head)
+	if b := findNode(doc, atom.Base); b != nil && base != nil {
+		if href, err := url.Parse(attr(b, "href")); err == nil {
+			base = base.ResolveReference(href)
+		}
+	}
+	im := &importer{opts: opts, base: base, fetcher: f}
+
+	var buf bytes.Buffer
+	if opts.KeepHead && head != nil {
+		for _, n := range headAssets(head) {
+			html.Render(&buf, im.rewrite(n))
+			buf.WriteByte('\n')
+		}
+	}
+
+	// 将body节点内部内容转换为HTML字符串
+	im.rewrite(bodyNode)
+	buf.WriteString(strings.TrimSpace(renderBodyContent(bodyNode)))
+	page.Content = bytes.TrimSpace(buf.Bytes())
+	page.Assets = im.assets
+	page.Links = im.links
+	return page, nil
+}
+
+// headAssets head 中需要带到页面内容的节点：样式表、样式、脚本和 meta
+// 跳过 charset 和 http-equiv meta
+func headAssets(head *html.Node) []*html.Node {
+	var nodes []*html.Node
+	for c := head.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode {
+			continue
+		}
+		switch c.DataAtom {
+		case atom.Meta:
+			if attr(c, "charset") != "" || attr(c, "http-equiv") != "" {
+				continue
+			}
+		case atom.Link:
+			if !strings.EqualFold(attr(c, "rel"), "stylesheet") {
+				continue
+			}
+		case atom.Script, atom.Style:
+		default:
+			continue
+		}
+		nodes = append(nodes, c)
+	}
+	return nodes
+}
+
+// rewrite 解析节点及其子节点中的资源地址，InlineAssets 时内联图片和样式表
+// 返回处理后的节点，样式表内联时为替换它的 <style>
+func (im *importer) rewrite(n *html.Node) *html.Node {
+	if n.Type == html.ElementNode {
+		for i := range n.Attr {
+			a := &n.Attr[i]
+			switch {
+			case a.Key == "style":
+				a.Val = im.rewriteCSS(a.Val, im.base, n.Data, a.Key)
+			case a.Key == "srcset":
+				a.Val = im.rewriteSrcset(n.Data, a.Val)
+			case slices.Contains(urlAttrs, a.Key):
+				a.Val = im.resolve(n.Data, a.Key, a.Val)
+			}
+		}
+		if href := attr(n, "href"); n.DataAtom == atom.A && strings.HasPrefix(href, "http") {
+			im.links = append(im.links, href)
+		}
+		if n.DataAtom == atom.Style && n.FirstChild != nil && n.FirstChild.Type == html.TextNode {
+			n.FirstChild.Data = im.rewriteCSS(n.FirstChild.Data, im.base, n.Data, "")
+		}
+		if im.opts.InlineAssets {
+			n = im.inline(n)
+		}
+	}
+
+	for c := n.FirstChild; c != nil; {
+		// inline 可能替换当前节点
+		next := c.NextSibling
+		im.rewrite(c)
+		c = next
+	}
+	return n
+}
+
+// resolve 将地址解析为绝对地址，锚点、data、javascript 等地址保持不变
+func (im *importer) resolve(tag, key, val string) string {
+	abs, ok := resolveURL(im.base, val)
+	if !ok || abs == val {
+		return val
+	}
+	im.assets = append(im.assets, assetRewrite{Tag: tag, Attr: key, Original: val, URL: abs, Result: assetResolved})
+	return abs
+}
+
+// resolveURL 按 base 解析相对地址
+func resolveURL(base *url.URL, val string) (string, bool) {
+	val = strings.TrimSpace(val)
+	if base == nil || val == "" || strings.HasPrefix(val, "#") {
+		return "", false
+	}
+	ref, err := url.Parse(val)
+	if err != nil {
+		return "", false
+	}
+	if ref.Scheme != "" && ref.Scheme != "http" && ref.Scheme != "https" {
+		return "", false
+	}
+	return base.ResolveReference(ref).String(), true
+}
+
+func (im *importer) rewriteSrcset(tag, srcset string) string {
+	candidates := strings.Split(srcset, ",")
+	for i, c := range candidates {
+		fields := strings.Fields(c)
+		if len(fields) == 0 {
+			continue
+		}
+		fields[0] = im.resolve(tag, "srcset", fields[0])
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// rewriteCSS 按 base 解析 CSS 中 url(...) 的地址
+func (im *importer) rewriteCSS(css string, base *url.URL, tag, key string) string {
+	return cssURLPattern.ReplaceAllStringFunc(css, func(m string) string {
+		sub := cssURLPattern.FindStringSubmatch(m)
+		abs, ok := resolveURL(base, sub[2])
+		if !ok || abs == sub[2] {
+			return m
+		}
+		im.assets = append(im.assets, assetRewrite{Tag: tag, Attr: cmp.Or(key, "url()"), Original: sub[2], URL: abs, Result: assetResolved})
+		return `url("` + abs + `")`
+	})
+}
+
+// inline 将图片内联为 data URI，将样式表替换为 <style>，返回处理后的节点
+func (im *importer) inline(n *html.Node) *html.Node {
+	switch {
+	case n.DataAtom == atom.Img:
+		for i, a := range n.Attr {
+			if a.Key != "src" || !strings.HasPrefix(a.Val, "http") {
+				continue
+			}
+			body, contentType, err := im.fetchAsset(a.Val)
+			rec := assetRewrite{Tag: "img", Attr: "src", Original: a.Val, URL: a.Val, Result: assetSkipped, Size: len(body)}
+			switch {
+			case err != nil:
+				rec.Reason = err.Error()
+			case !strings.HasPrefix(contentType, "image/"):
+				rec.Reason = "not an image: " + contentType
+			default:
+				n.Attr[i].Val = "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(body)
+				rec.Result = assetInlined
+			}
+			im.assets = append(im.assets, rec)
+		}
+		return n
+	case n.DataAtom == atom.Link && strings.EqualFold(attr(n, "rel"), "stylesheet"):
+		href := attr(n, "href")
+		if !strings.HasPrefix(href, "http") {
+			return n
+		}
+		body, _, err := im.fetchAsset(href)
+		rec := assetRewrite{Tag: "link", Attr: "href", Original: href, URL: href, Result: assetSkipped, Size: len(body)}
+		if err != nil {
+			rec.Reason = err.Error()
+			im.assets = append(im.assets, rec)
+			return n
+		}
+		// 样式表中的地址相对于样式表本身
+		cssBase, _ := url.Parse(href)
+		style := &html.Node{Type: html.ElementNode, Data: "style", DataAtom: atom.Style}
+		style.AppendChild(&html.Node{Type: html.TextNode, Data: im.rewriteCSS(string(body), cssBase, "style", "")})
+		n.Parent.InsertBefore(style, n)
+		n.Parent.RemoveChild(n)
+		rec.Result = assetInlined
+		im.assets = append(im.assets, rec)
+		return style
+	}
+	return n
+}
+
+// fetchAsset 下载资源，超过 InlineMaxSize 时返回错误
+func (im *importer) fetchAsset(rawURL string) ([]byte, string, error) {
+	res, err := im.fetcher.get(rawURL, im.opts.InlineMaxSize)
+	if err != nil {
+		return nil, "", err
+	}
+	contentType, _, _ := mime.ParseMediaType(res.ContentType)
+	if contentType == "" {
+		contentType = http.DetectContentType(res.Body)
+	}
+	return res.Body, contentType, nil
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// 查找节点
+func findNode(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if result := findNode(c, a); result != nil {
+			return result
+		}
+	}
+	return nil
+}
+
+// 将body节点内部内容渲染为HTML字符串（不包含body标签本身）
+func renderBodyContent(bodyNode *html.Node) string {
+	var buf bytes.Buffer
+	for c := bodyNode.FirstChild; c != nil; c = c.NextSibling {
+		html.Render(&buf, c)
+	}
+	return buf.String()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const sourcePage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="X-UA-Compatible" content="IE=edge">
+<title> 降血脂 </title>
+<meta name="description" content="描述">
+<link rel="stylesheet" href="css/a.css">
+<link rel="icon" href="/favicon.ico">
+<style>p { color: red }</style>
+<script src="/js/app.js"></script>
+</head>
+<body>
+<p>正文</p>
+</body>
+</html>`
+
+func TestFetchPage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(sourcePage))
+	}))
+	defer srv.Close()
+
+	page, err := fetchPage(srv.URL+"/landing/index.html", importOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(page.Content) != "<p>正文</p>" || page.Title != "降血脂" {
+		t.Errorf("body only: %q, title %q", page.Content, page.Title)
+	}
+
+	page, err = fetchPage(srv.URL+"/landing/index.html", importOptions{KeepHead: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		`<meta name="description" content="描述"/>`,
+		`<link rel="stylesheet" href="` + srv.URL + `/landing/css/a.css"/>`,
+		`<style>p { color: red }</style>`,
+		`<script src="` + srv.URL + `/js/app.js"></script>`,
+		`<p>正文</p>`,
+	}, "\n")
+	if string(page.Content) != want {
+		t.Errorf("keep head:\n got %s\nwant %s", page.Content, want)
+	}
+}
+
+func TestImportAssets(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n0000")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/p/index.html":
+			w.Write([]byte(`<html><head><link rel="stylesheet" href="../css/x.css"></head><body>` +
+				`<img src="./img/a.png"><img src="/img/big.png" srcset="/img/a.png 1x, img/b.png 2x">` +
+				`<div style="background: url('bg.jpg')"></div><a href="#top">top</a><a href="tel:400">call</a></body></html>`))
+		case "/css/x.css":
+			w.Header().Set("Content-Type", "text/css")
+			w.Write([]byte(`body { background: url(../img/a.png) }`))
+		case "/p/img/a.png", "/img/a.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(png)
+		case "/img/big.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(make([]byte, 100))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	page, err := fetchPage(srv.URL+"/p/index.html", importOptions{KeepHead: true, InlineAssets: true, InlineMaxSize: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(page.Content)
+	for _, want := range []string{
+		`<style>body { background: url("` + srv.URL + `/img/a.png") }</style>`,
+		`<img src="data:image/png;base64,`,
+		`<img src="` + srv.URL + `/img/big.png" srcset="` + srv.URL + `/img/a.png 1x, ` + srv.URL + `/p/img/b.png 2x"/>`,
+		`url(&#34;` + srv.URL + `/p/bg.jpg&#34;)`,
+		`<a href="#top">`,
+		`<a href="tel:400">`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content missing %s:\n%s", want, content)
+		}
+	}
+
+	results := make(map[string]int)
+	for _, a := range page.Assets {
+		results[a.Result]++
+	}
+	// css、a.png 内联，big.png 超过大小限制
+	if results[assetInlined] != 2 || results[assetSkipped] != 1 {
+		t.Errorf("assets: %+v", page.Assets)
+	}
+}
+
+func TestDecodeHTML(t *testing.T) {
+	gbk := func(s string) []byte {
+		b, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	tests := []struct {
+		name        string
+		content     []byte
+		contentType string
+		charset     string
+		want        string
+		wantCharset string
+	}{
+		{"utf-8", []byte("<p>正文</p>"), "", "", "<p>正文</p>", "utf-8"},
+		{"bom", []byte("\xef\xbb\xbf<p>正文</p>"), "", "", "<p>正文</p>", "utf-8"},
+		{"header", gbk("<p>正文</p>"), "text/html; charset=GBK", "", "<p>正文</p>", "gbk"},
+		{"meta", gbk(`<meta charset="gb2312"><p>正文</p>`), "text/html", "", `<meta charset="utf-8"><p>正文</p>`, "gbk"},
+		{"sniff", gbk("<p>正文</p>"), "", "", "<p>正文</p>", "gb18030"},
+		{"flag", gbk("<p>正文</p>"), "text/html; charset=utf-8", "gbk", "<p>正文</p>", "gbk"},
+		{"long ascii prefix", []byte(strings.Repeat("<p>ascii</p>", 100) + "<p>正文</p>"), "", "", strings.Repeat("<p>ascii</p>", 100) + "<p>正文</p>", "utf-8"},
+		{"ascii", []byte("<p>ascii</p>"), "", "", "<p>ascii</p>", "utf-8"},
+	}
+	for _, tt := range tests {
+		got, name, err := decodeHTML(tt.content, tt.contentType, tt.charset)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want || name != tt.wantCharset {
+			t.Errorf("%s: got %q (%s), want %q (%s)", tt.name, got, name, tt.want, tt.wantCharset)
+		}
+	}
+
+	if _, _, err := decodeHTML(nil, "", "nope"); err == nil {
+		t.Error("expected error for unknown charset")
+	}
+}
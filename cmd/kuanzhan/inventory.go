@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	inventoryFile string // 本地站点清单文件
+	useCached     bool   // 读命令使用本地清单
+)
+
+// bbolt bucket 名称
+var (
+	sitesBucket     = []byte("sites")     // sites/<account>/<siteId> -> siteRecord
+	syncedBucket    = []byte("synced")    // synced/<account> -> 最后同步时间
+	snapshotsBucket = []byte("snapshots") // snapshots/<account>/<unix nano> -> []siteRecord
+)
+
+// errNotInInventory 本地清单中没有该站点
+var errNotInInventory = errors.New("not in local inventory, run kuanzhan sync first")
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&inventoryFile, "inventory", "", "本地站点清单文件，默认 ~/.kuanzhan/inventory.db")
+	rootCmd.PersistentFlags().BoolVar(&useCached, "cached", false, "读命令使用 kuanzhan sync 同步的本地清单，不请求快站接口")
+}
+
+// pageRecord 页面记录
+type pageRecord struct {
+	PageID int    `json:"pageId"`
+	Title  string `json:"title"`
+}
+
+// siteRecord 站点及其页面，本地清单中存储的记录
+type siteRecord struct {
+	Account              string       `json:"account"`
+	SiteID               int          `json:"siteId"`
+	SiteName             string       `json:"siteName"`
+	SiteDomain           string       `json:"siteDomain"`
+	PackageName          string       `json:"packageName"`
+	SiteStatus           string       `json:"siteStatus"`
+	PackageRemainingDays int          `json:"packageRemainingDays"`
+	Pages                []pageRecord `json:"pages"`
+	FetchedAt            time.Time    `json:"fetchedAt"`
+	Error                string       `json:"error,omitempty"`
+}
+
+// siteRow 站点记录，不包含页面
+func (rec *siteRecord) siteRow() siteRow {
+	site := siteRow{
+		SiteID:               rec.SiteID,
+		SiteName:             rec.SiteName,
+		SiteDomain:           rec.SiteDomain,
+		PackageName:          rec.PackageName,
+		SiteStatus:           rec.SiteStatus,
+		PackageRemainingDays: rec.PackageRemainingDays,
+		Error:                rec.Error,
+	}
+	if !rec.FetchedAt.IsZero() {
+		site.FetchedAt = rec.FetchedAt.Local().Format(time.DateTime)
+	}
+	return site
+}
+
+// rows 展开为 list 使用的记录，没有页面或 onlySite 时只返回站点记录
+func (rec *siteRecord) rows(onlySite bool) []siteRow {
+	site := rec.siteRow()
+	if onlySite || len(rec.Pages) == 0 {
+		return []siteRow{site}
+	}
+
+	rows := make([]siteRow, 0, len(rec.Pages))
+	for _, page := range rec.Pages {
+		rows = append(rows, site.withPage(page.PageID, page.Title))
+	}
+	return rows
+}
+
+// inventory 本地站点清单，bbolt 文件
+type inventory struct {
+	db *bolt.DB
+}
+
+// inventoryPath 本地清单文件路径
+func inventoryPath() string {
+	if inventoryFile != "" {
+		return inventoryFile
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "inventory.db"
+	}
+	return filepath.Join(home, ".kuanzhan", "inventory.db")
+}
+
+// openInventory 打开本地清单，文件不存在时创建
+func openInventory(path string) (*inventory, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open inventory %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{sitesBucket, syncedBucket, snapshotsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &inventory{db: db}, nil
+}
+
+// Close 关闭本地清单
+func (inv *inventory) Close() error {
+	return inv.db.Close()
+}
+
+func siteKey(siteId int) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(siteId))
+}
+
+// PutSites 写入站点记录
+func (inv *inventory) PutSites(records ...*siteRecord) error {
+	return inv.db.Update(func(tx *bolt.Tx) error {
+		for _, rec := range records {
+			b, err := tx.Bucket(sitesBucket).CreateBucketIfNotExists([]byte(rec.Account))
+			if err != nil {
+				return err
+			}
+			v, err := json.Marshal(rec)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(siteKey(rec.SiteID), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteSites 删除站点记录
+func (inv *inventory) DeleteSites(account string, siteIds ...int) error {
+	return inv.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(sitesBucket).Bucket([]byte(account))
+		if b == nil {
+			return nil
+		}
+		for _, siteId := range siteIds {
+			if err := b.Delete(siteKey(siteId)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Sites 账号下的所有站点记录，按站点ID排序
+func (inv *inventory) Sites(account string) ([]*siteRecord, error) {
+	var records []*siteRecord
+	err := inv.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(sitesBucket).Bucket([]byte(account))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			rec := &siteRecord{}
+			if err := json.Unmarshal(v, rec); err != nil {
+				return fmt.Errorf("inventory %s/%d: %w", account, binary.BigEndian.Uint64(k), err)
+			}
+			records = append(records, rec)
+			return nil
+		})
+	})
+	return records, err
+}
+
+// Site 单个站点记录，不存在时返回 errNotInInventory
+func (inv *inventory) Site(account string, siteId int) (*siteRecord, error) {
+	var rec *siteRecord
+	err := inv.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(sitesBucket).Bucket([]byte(account))
+		if b == nil {
+			return nil
+		}
+		v := b.Get(siteKey(siteId))
+		if v == nil {
+			return nil
+		}
+		rec = &siteRecord{}
+		return json.Unmarshal(v, rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, fmt.Errorf("site %d (profile %s): %w", siteId, account, errNotInInventory)
+	}
+	return rec, nil
+}
+
+// SetSynced 记录账号的同步时间
+func (inv *inventory) SetSynced(account string, t time.Time) error {
+	return inv.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(syncedBucket).Put([]byte(account), []byte(t.UTC().Format(time.RFC3339)))
+	})
+}
+
+// Synced 账号最后一次同步时间，从未同步时返回零值
+func (inv *inventory) Synced(account string) (time.Time, error) {
+	var t time.Time
+	err := inv.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(syncedBucket).Get([]byte(account))
+		if v == nil {
+			return nil
+		}
+		var err error
+		t, err = time.Parse(time.RFC3339, string(v))
+		return err
+	})
+	return t, err
+}
+
+// snapshot 一次同步后账号的完整站点状态
+type snapshot struct {
+	Time  time.Time
+	Sites []*siteRecord
+}
+
+// sameContent 比较两次记录的内容，忽略获取时间
+func sameContent(a, b []*siteRecord) bool {
+	return slices.EqualFunc(a, b, func(x, y *siteRecord) bool {
+		x2, y2 := *x, *y
+		x2.FetchedAt, y2.FetchedAt = time.Time{}, time.Time{}
+		xb, _ := json.Marshal(x2)
+		yb, _ := json.Marshal(y2)
+		return string(xb) == string(yb)
+	})
+}
+
+func timeKey(t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(t.UnixNano()))
+}
+
+// PutSnapshot 记录账号在 t 时刻的站点状态，与上一次快照内容相同时不记录
+func (inv *inventory) PutSnapshot(account string, t time.Time, records []*siteRecord) error {
+	return inv.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(snapshotsBucket).CreateBucketIfNotExists([]byte(account))
+		if err != nil {
+			return err
+		}
+		if _, v := b.Cursor().Last(); v != nil {
+			var last []*siteRecord
+			if err := json.Unmarshal(v, &last); err == nil && sameContent(last, records) {
+				return nil
+			}
+		}
+		v, err := json.Marshal(records)
+		if err != nil {
+			return err
+		}
+		return b.Put(timeKey(t), v)
+	})
+}
+
+// Snapshots 账号在 since 之后的快照，按时间排序
+// 结果包含 since 之前的最后一个快照，作为 since 时刻的状态
+func (inv *inventory) Snapshots(account string, since time.Time) ([]snapshot, error) {
+	var snapshots []snapshot
+	err := inv.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(snapshotsBucket).Bucket([]byte(account))
+		if b == nil {
+			return nil
+		}
+
+		c := b.Cursor()
+		k, v := c.Seek(timeKey(since))
+		if k == nil {
+			k, v = c.Last()
+		} else if string(k) != string(timeKey(since)) {
+			if pk, pv := c.Prev(); pk != nil {
+				k, v = pk, pv
+			} else {
+				k, v = c.First()
+			}
+		}
+		for ; k != nil; k, v = c.Next() {
+			s := snapshot{Time: time.Unix(0, int64(binary.BigEndian.Uint64(k)))}
+			if err := json.Unmarshal(v, &s.Sites); err != nil {
+				return fmt.Errorf("snapshot %s/%s: %w", account, s.Time, err)
+			}
+			snapshots = append(snapshots, s)
+		}
+		return nil
+	})
+	return snapshots, err
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+	"pkg.blksails.net/kuanzhan"
+)
+
+// --fail-on-error 策略
+const (
+	failOnAny   = "any"   // 任一站点失败时退出码非0
+	failOnAll   = "all"   // 所有站点都失败时退出码非0
+	failOnNever = "never" // 总是以0退出
+)
+
+var (
+	listConcurrency int    // 每个账号并发获取站点信息的数量
+	failOnError     string // 失败退出策略
+
+	filterStatus   []string // 站点状态过滤
+	filterPackage  []string // 套餐类型过滤
+	filterName     string   // 站点名称正则
+	filterDomain   string   // 站点域名 glob
+	expiringWithin string   // 套餐剩余天数过滤
+	sortBy         []string // 排序字段
+	listColumns    []string // 输出列
+)
+
+var siteListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "站点列表",
+	Long:  "快站快速获取站点列表，--profile all 或逗号分隔的 profile 列表可以同时列出多个账号的站点",
+	Run: func(cmd *cobra.Command, args []string) {
+		if !slices.Contains([]string{failOnAny, failOnAll, failOnNever}, failOnError) {
+			log.Fatalf("invalid --fail-on-error %q", failOnError)
+		}
+		filter, err := newSiteFilter(filterStatus, filterPackage, filterName, filterDomain, expiringWithin)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var (
+			accounts    []string
+			accountRows [][]siteRow
+		)
+		if useCached {
+			inv, err := openInventory(inventoryPath())
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer inv.Close()
+
+			accounts = inventoryAccounts()
+			for _, account := range accounts {
+				rows, err := cachedAccountRows(inv, account)
+				if err != nil {
+					rows = []siteRow{{Error: err.Error()}}
+				}
+				accountRows = append(accountRows, rows)
+			}
+		} else {
+			set := newClientSet()
+			for _, a := range set.Accounts() {
+				accounts = append(accounts, a.Name)
+			}
+			accountRows = make([][]siteRow, len(accounts))
+			set.ForEach(context.Background(), func(ctx context.Context, a *kuanzhan.Account) error {
+				rows, err := listAccountRows(ctx, a.Client, listConcurrency)
+				if err != nil {
+					rows = []siteRow{{Error: err.Error()}}
+				}
+				accountRows[slices.Index(accounts, a.Name)] = rows
+				return nil
+			})
+		}
+
+		rows := []siteRow{}
+		for i, accountRows := range accountRows {
+			for _, row := range accountRows {
+				if multiProfile() {
+					row.Account = accounts[i]
+				}
+				rows = append(rows, row)
+			}
+		}
+
+		failed, total := countFailures(rows)
+
+		rows = filter.apply(rows)
+		if err := sortRows(rows, sortBy); err != nil {
+			log.Fatal(err)
+		}
+
+		columns := siteColumns
+		if multiProfile() {
+			columns = slices.Concat([]outputColumn{accountColumn}, columns)
+		}
+		if filter.ExpiringWithin >= 0 {
+			columns = slices.Concat(columns, []outputColumn{remainingDaysColumn})
+		}
+		if !onlySite {
+			columns = slices.Concat(columns, pageColumns)
+		}
+		if useCached {
+			columns = slices.Concat(columns, []outputColumn{fetchedAtColumn})
+		}
+		if failed > 0 {
+			columns = slices.Concat(columns, []outputColumn{errorColumn})
+		}
+		if len(listColumns) > 0 {
+			if columns, err = selectColumns(listColumns); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err := renderOutput(os.Stdout, columns, rows); err != nil {
+			log.Fatal(err)
+		}
+
+		if failed > 0 {
+			log.Printf("%d of %d sites failed", failed, total)
+		}
+		if failed > 0 && (failOnError == failOnAny || failOnError == failOnAll && failed == total) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	siteListCmd.Flags().IntVar(&listConcurrency, "concurrency", 10, "每个账号并发获取站点信息的数量")
+	siteListCmd.Flags().StringVar(&failOnError, "fail-on-error", failOnAny, "站点获取失败时的退出策略: any|all|never")
+	siteListCmd.Flags().StringSliceVar(&filterStatus, "status", nil, "只显示指定状态的站点，如 ONLINE，可指定多个")
+	siteListCmd.Flags().StringSliceVar(&filterPackage, "package", nil, "只显示指定套餐类型的站点，可指定多个")
+	siteListCmd.Flags().StringVar(&filterName, "name", "", "站点名称正则表达式")
+	siteListCmd.Flags().StringVar(&filterDomain, "domain", "", "站点域名 glob，如 '*.kuaizhan.com'")
+	siteListCmd.Flags().StringVar(&expiringWithin, "expiring-within", "", "只显示套餐剩余天数不超过指定值的站点，如 30d")
+	siteListCmd.Flags().StringSliceVar(&sortBy, "sort-by", nil, "排序字段，可指定多个，字段名前加 - 表示降序，如 -packageRemainingDays,siteId")
+	siteListCmd.Flags().StringSliceVar(&listColumns, "columns", nil, "输出列（字段名），如 siteId,siteName,packageRemainingDays")
+}
+
+// listAccountRows 获取账号下所有站点和页面的记录，按站点ID排序
+// 单个站点失败时记录在该站点的 Error 字段中，只有获取站点列表失败时返回错误
+func listAccountRows(ctx context.Context, client *kuanzhan.Client, concurrency int) ([]siteRow, error) {
+	resp, err := client.GetSiteIds()
+	if err != nil {
+		return nil, fmt.Errorf("GetSiteIds: %w", err)
+	}
+
+	rows := []siteRow{}
+	for _, rec := range fetchSiteRecords(ctx, client, "", resp.Data.SiteIds, concurrency, !onlySite) {
+		rows = append(rows, rec.rows(onlySite)...)
+	}
+	return rows, nil
+}
+
+// fetchSiteRecords 并发获取站点记录，结果按站点ID排序
+func fetchSiteRecords(ctx context.Context, client *kuanzhan.Client, account string, siteIds []int, concurrency int, withPages bool) []*siteRecord {
+	siteIds = slices.Clone(siteIds)
+	slices.Sort(siteIds)
+
+	records := make([]*siteRecord, len(siteIds))
+	g, ctx := errgroup.WithContext(ctx)
+	if concurrency > 0 {
+		g.SetLimit(concurrency)
+	}
+	for i, siteId := range siteIds {
+		g.Go(func() error {
+			if ctx.Err() != nil {
+				records[i] = &siteRecord{Account: account, SiteID: siteId, Error: ctx.Err().Error()}
+				return nil
+			}
+			records[i] = fetchSiteRecord(client, account, siteId, withPages)
+			return nil
+		})
+	}
+	g.Wait()
+	return records
+}
+
+// fetchSiteRecord 获取单个站点的信息和页面，失败时记录在 Error 字段中
+func fetchSiteRecord(client *kuanzhan.Client, account string, siteId int, withPages bool) *siteRecord {
+	rec := &siteRecord{Account: account, SiteID: siteId, FetchedAt: time.Now()}
+	siteInfo, err := client.GetSiteInfo(siteId)
+	if err != nil {
+		rec.Error = "GetSiteInfo: " + err.Error()
+		return rec
+	}
+	rec.SiteName = siteInfo.Data.SiteName
+	rec.SiteDomain = siteInfo.Data.SiteDomain
+	rec.PackageName = siteInfo.Data.PackageName
+	rec.SiteStatus = siteInfo.Data.SiteStatus
+	rec.PackageRemainingDays = siteInfo.Data.PackageRemainingDays
+	if !withPages {
+		return rec
+	}
+
+	pageNames, err := client.GetPageName(siteId)
+	if err != nil {
+		rec.Error = "GetPageName: " + err.Error()
+		return rec
+	}
+	rec.Pages = make([]pageRecord, 0, len(pageNames.Data))
+	for _, pageName := range pageNames.Data {
+		rec.Pages = append(rec.Pages, pageRecord{PageID: pageName.PageId, Title: pageName.Title})
+	}
+	return rec
+}
+
+// countFailures 统计失败的站点数和站点总数，账号级别的失败计为一个站点
+func countFailures(rows []siteRow) (failed, total int) {
+	type key struct {
+		account string
+		siteId  int
+	}
+	seen := make(map[key]bool)
+	for _, row := range rows {
+		k := key{row.Account, row.SiteID}
+		if _, ok := seen[k]; !ok {
+			total++
+			seen[k] = false
+		}
+		if row.Error != "" && !seen[k] {
+			seen[k] = true
+			failed++
+		}
+	}
+	return failed, total
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSite 测试快站账号中的站点
+type fakeSite struct {
+	Name          string
+	Domain        string
+	Status        string
+	Package       string
+	RemainingDays int
+	Pages         map[int]string
+	InfoErr       string
+	PagesErr      string
+	Contents      map[int]string // 页面内容，ModifyPageJs 写入
+	Published     map[int]int    // 页面发布次数
+}
+
+// fakeKuanzhan 模拟快站接口，按路径分发
+type fakeKuanzhan struct {
+	mu     sync.Mutex
+	sites  map[int]*fakeSite
+	calls  []string
+	nextID int
+}
+
+func (f *fakeKuanzhan) newID() int {
+	f.nextID++
+	return 1000 + f.nextID
+}
+
+// pageSite 页面所属的站点
+func (f *fakeKuanzhan) pageSite(pageId int) *fakeSite {
+	for _, site := range f.sites {
+		if _, ok := site.Pages[pageId]; ok {
+			return site
+		}
+	}
+	return nil
+}
+
+func (f *fakeKuanzhan) reply(w http.ResponseWriter, data any) {
+	json.NewEncoder(w).Encode(map[string]any{"code": 200, "msg": "ok", "data": data})
+}
+
+func (f *fakeKuanzhan) fail(w http.ResponseWriter, msg string) {
+	json.NewEncoder(w).Encode(map[string]any{"code": 500, "msg": msg})
+}
+
+func (f *fakeKuanzhan) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	r.ParseForm()
+	path := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	f.calls = append(f.calls, path)
+	siteId, _ := strconv.Atoi(r.Form.Get("siteId"))
+	site := f.sites[siteId]
+	pageId, _ := strconv.Atoi(r.Form.Get("pageId"))
+
+	switch path {
+	case "getSiteIds":
+		ids := []int{}
+		for id := range f.sites {
+			ids = append(ids, id)
+		}
+		f.reply(w, map[string]any{"siteIds": ids})
+	case "getSiteInfo":
+		if site == nil || site.InfoErr != "" {
+			f.fail(w, "site error")
+			return
+		}
+		f.reply(w, map[string]any{
+			"siteId":               strconv.Itoa(siteId),
+			"siteName":             site.Name,
+			"siteDomain":           site.Domain,
+			"siteStatus":           site.Status,
+			"packageName":          site.Package,
+			"packageRemainingDays": site.RemainingDays,
+		})
+	case "getPageName":
+		if site == nil || site.PagesErr != "" {
+			f.fail(w, "page error")
+			return
+		}
+		pages := []map[string]any{}
+		for id, title := range site.Pages {
+			pages = append(pages, map[string]any{"pageId": id, "title": title})
+		}
+		f.reply(w, pages)
+	case "createSite":
+		id := f.newID()
+		f.sites[id] = &fakeSite{Name: r.Form.Get("siteName"), Domain: r.Form.Get("domain"), Status: "ONLINE", Pages: map[int]string{}}
+		f.reply(w, map[string]any{"siteId": strconv.Itoa(id), "siteDomain": r.Form.Get("domain")})
+	case "publishSite", "openBusinessPackage":
+		if site == nil {
+			f.fail(w, "no site")
+			return
+		}
+		if path == "openBusinessPackage" {
+			site.Package = r.Form.Get("businessType")
+			site.RemainingDays = 365
+		}
+		f.reply(w, map[string]any{"url": site.Domain})
+	case "changeDomain":
+		site.Domain = r.Form.Get("domain")
+		f.reply(w, map[string]any{})
+	case "updateSiteSetting":
+		site.Name = r.Form.Get("siteName")
+		f.reply(w, map[string]any{})
+	case "createSitePage":
+		if site == nil {
+			f.fail(w, "no site")
+			return
+		}
+		id := f.newID()
+		site.Pages[id] = ""
+		f.reply(w, map[string]any{"pageId": id})
+	case "updatePageName":
+		var body struct {
+			PageId   int    `json:"pageId"`
+			PageName string `json:"pageName"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		site := f.pageSite(body.PageId)
+		if site == nil {
+			f.fail(w, "no page")
+			return
+		}
+		site.Pages[body.PageId] = body.PageName
+		f.reply(w, map[string]any{})
+	case "modifyPageJs", "publishPage":
+		if site == nil || f.pageSite(pageId) != site {
+			f.fail(w, "no page")
+			return
+		}
+		if path == "modifyPageJs" {
+			if site.Contents == nil {
+				site.Contents = map[int]string{}
+			}
+			site.Contents[pageId] = r.Form.Get("content")
+		} else {
+			if site.Published == nil {
+				site.Published = map[int]int{}
+			}
+			site.Published[pageId]++
+		}
+		f.reply(w, map[string]any{"status": "ok", "url": pageURL(site.Domain, pageId)})
+	case "batchModifyPublishPageJs":
+		var body struct {
+			PageIds []int  `json:"pageIds"`
+			Content string `json:"content"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		for _, pageId := range body.PageIds {
+			site := f.pageSite(pageId)
+			if site == nil {
+				f.fail(w, "no page")
+				return
+			}
+			if site.Contents == nil {
+				site.Contents = map[int]string{}
+			}
+			site.Contents[pageId] = body.Content
+			if site.Published == nil {
+				site.Published = map[int]int{}
+			}
+			site.Published[pageId]++
+		}
+		f.reply(w, "task-"+strconv.Itoa(f.newID()))
+	default:
+		f.fail(w, "unsupported "+path)
+	}
+}
+
+func TestListAccountRows(t *testing.T) {
+	fake := &fakeKuanzhan{sites: map[int]*fakeSite{
+		3: {Name: "c", Domain: "c.kuaizhan.com", Pages: map[int]string{30: "页面30"}, PagesErr: "x"},
+		1: {Name: "a", Domain: "a.kuaizhan.com", Pages: map[int]string{10: "页面10"}},
+		2: {Name: "b", InfoErr: "x"},
+		4: {Name: "d", Domain: "d.kuaizhan.com"},
+	}}
+	client := newTestServer(t, fake.ServeHTTP)
+
+	rows, err := listAccountRows(context.Background(), client, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 4 {
+		t.Fatalf("got %d rows: %+v", len(rows), rows)
+	}
+	for i, want := range []int{1, 2, 3, 4} {
+		if rows[i].SiteID != want {
+			t.Errorf("rows[%d].SiteID = %d, want %d", i, rows[i].SiteID, want)
+		}
+	}
+	if rows[0].PageID != 10 || rows[0].PageURL != "a.kuaizhan.com/10" || rows[0].Error != "" {
+		t.Errorf("site 1: %+v", rows[0])
+	}
+	if !strings.HasPrefix(rows[1].Error, "GetSiteInfo") {
+		t.Errorf("site 2: %+v", rows[1])
+	}
+	if rows[2].SiteName != "c" || !strings.HasPrefix(rows[2].Error, "GetPageName") {
+		t.Errorf("site 3: %+v", rows[2])
+	}
+	if rows[3].PageID != 0 || rows[3].Error != "" {
+		t.Errorf("site 4: %+v", rows[3])
+	}
+
+	failed, total := countFailures(rows)
+	if failed != 2 || total != 4 {
+		t.Errorf("countFailures = %d/%d", failed, total)
+	}
+}
@@ -1,31 +1,32 @@
 package main
 
 import (
-	"bytes"
-	"context"
+	"cmp"
 	"fmt"
 	"log"
 	"math/rand"
-	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
-	"strings"
 	"time"
 
-	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"golang.org/x/net/html"
-	"golang.org/x/sync/semaphore"
 	"pkg.blksails.net/kuanzhan"
 )
 
-var profile string = "default"
+var (
+	profile string = "default"
+	cfgFile string // 指定配置文件
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "kuanzhan",
 	Short: "kuanzhan",
 	Long:  "kuanzhan",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		applyProfileDefaults(cmd)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		cmd.Help()
 	},
@@ -66,78 +67,14 @@ var createSiteCmd = &cobra.Command{
 			}
 
 			log.Println("create site", resp.Data.SiteID)
+			site := newResource(resourceSite, "create-site", int(siteId), 0, createSiteName, "")
+			site.Domain = normalizeDomain(cmp.Or(resp.Data.SiteDomain, uniqueDomain))
+			site.Package = businessType
+			recordResources(site)
 		}
 	},
 }
 
-const maxConcurrent = 10
-
-var siteListCmd = &cobra.Command{
-	Use:   "list",
-	Short: "站点列表",
-	Long:  "快站快速获取站点列表",
-	Run: func(cmd *cobra.Command, args []string) {
-		client := newClient()
-		resp, err := client.GetSiteIds()
-		if err != nil {
-			log.Fatal(err)
-		}
-		table := tablewriter.NewWriter(os.Stdout)
-		table.Header("站点ID", "站点名称", "套餐类型", "站点URL", "站点状态", "页面ID", "页面名称", "页面URL")
-
-		var (
-			rows = [][]string{}
-			sem  = semaphore.NewWeighted(maxConcurrent)
-			ctx  = context.Background()
-		)
-
-		for _, siteId := range resp.Data.SiteIds {
-			if err := sem.Acquire(ctx, 1); err != nil {
-				log.Fatal(err)
-			}
-
-			go func(siteId int) {
-				defer sem.Release(1)
-				siteInfo, err := client.GetSiteInfo(siteId)
-				if err != nil {
-					log.Fatal(err)
-				}
-
-				prerow := []string{
-					fmt.Sprintf("%d", siteId),
-					siteInfo.Data.SiteName,
-					siteInfo.Data.SiteDomain,
-					siteInfo.Data.PackageName,
-					siteInfo.Data.SiteStatus,
-				}
-
-				if !onlySite {
-					pageNames, err := client.GetPageName(siteId)
-					if err != nil {
-						rows = append(rows, prerow)
-						return
-					}
-
-					for _, pageName := range pageNames.Data {
-						row := append(prerow, fmt.Sprintf("%d", pageName.PageId), pageName.Title, siteInfo.Data.SiteDomain+"/"+strconv.Itoa(pageName.PageId))
-						rows = append(rows, row)
-					}
-				} else {
-					rows = append(rows, prerow)
-				}
-
-			}(siteId)
-		}
-
-		if err := sem.Acquire(ctx, int64(maxConcurrent)); err != nil {
-			log.Printf("Failed to acquire semaphore: %v", err)
-		}
-
-		table.Bulk(rows)
-		table.Render()
-	},
-}
-
 var uploadSiteCmd = &cobra.Command{
 	Use:   "upload",
 	Short: "上传站点",
@@ -148,21 +85,37 @@ var uploadSiteCmd = &cobra.Command{
 		if sourceUrl == "" && localPath == "" {
 			log.Fatal("source-url or local-path is required")
 		}
+		if uploadWatch {
+			watchUpload()
+			return
+		}
 
 		if localPath != "" {
-			pagehtml, err = os.ReadFile(localPath)
+			pagehtml, err = readLocalPage(localPath, importOpts.Charset)
 			if err != nil {
 				log.Fatal(err)
 			}
 			log.Println("upload site ", localPath, " to site ", siteIds, " page ", pageSize, " pageIds ", pageIds)
 		} else {
-			pagehtml, err = downloadPage(sourceUrl)
+			page, err := fetchPage(sourceUrl, importOpts)
 			if err != nil {
 				log.Fatal(err)
 			}
+			pagehtml = page.Content
+			if page.Charset != "utf-8" {
+				log.Printf("%s: transcoded from %s to utf-8", sourceUrl, page.Charset)
+			}
+			printAssetReport(page)
+			if pageName == "" && importOpts.KeepHead {
+				pageName = page.Title
+			}
 			log.Println("upload site ", sourceUrl, " to site ", siteIds, " page ", pageSize, " pageIds ", pageIds)
 		}
 
+		if pageName == "" && !uploadDiff {
+			log.Fatal("--name is required")
+		}
+
 		var (
 			allPageIds []int
 		)
@@ -180,14 +133,64 @@ var uploadSiteCmd = &cobra.Command{
 			log.Println("task", resp.Data)
 			return
 		}
+		var tmpl *pageTemplate
+		if renderMode != "" {
+			tmpl, err = newPageTemplate(renderMode, pagehtml, valuesFile, setValues)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		if uploadDiff {
+			if len(pageIds) == 0 {
+				log.Println("no --page-ids, all pages will be created")
+				return
+			}
+			contents, _, err := pageContents(client, tmpl, pagehtml, siteIds, pageIds, nil, pageName)
+			if err != nil {
+				log.Fatal(err)
+			}
+			hashes, err := uploadedHashes(profile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := printUploadDiff(os.Stdout, pageIds, contents, hashes); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+
+		var contents map[int][]byte
+		pageSites := make(map[int]int)
 		if len(pageIds) > 0 {
-			for _, pageId := range pageIds {
+			contents, pageSites, err = pageContents(client, tmpl, pagehtml, siteIds, pageIds, nil, pageName)
+			if err != nil {
+				log.Fatal(err)
+			}
+			allPageIds = pageIds
+
+			// 只修改实际上传的页面的名称
+			if skipUnchanged {
+				hashes, err := uploadedHashes(profile)
+				if err != nil {
+					log.Fatal(err)
+				}
+				changed := changedPages(pageIds, contents, hashes)
+				if skipped := len(pageIds) - len(changed); skipped > 0 {
+					log.Printf("skip %d unchanged pages", skipped)
+				}
+				if len(changed) == 0 {
+					log.Println("all pages are unchanged, nothing to upload")
+					return
+				}
+				allPageIds = changed
+			}
+			for _, pageId := range allPageIds {
 				_, err := client.UpdatePageName(pageId, pageName)
 				if err != nil {
 					log.Fatal(err)
 				}
 			}
-			allPageIds = pageIds
 		}
 
 		for _, siteId := range siteIds {
@@ -208,6 +211,7 @@ var uploadSiteCmd = &cobra.Command{
 						log.Fatal(err)
 					}
 					sitePageIds = append(sitePageIds, resp.Data.PageId)
+					pageSites[resp.Data.PageId] = siteId
 				}
 
 				allPageIds = append(allPageIds, sitePageIds...)
@@ -218,11 +222,57 @@ var uploadSiteCmd = &cobra.Command{
 			log.Fatal("no page ids or site ids")
 		}
 
-		resp, err := client.BatchModifyPagePublishPageJs(siteIds, allPageIds, string(pagehtml), true, "")
-		if err != nil {
-			log.Fatal(err)
+		if contents == nil {
+			contents, pageSites, err = pageContents(client, tmpl, pagehtml, siteIds, allPageIds, pageSites, pageName)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		source := sourceUrl
+		if localPath != "" {
+			source, _ = filepath.Abs(localPath)
+		}
+
+		if tmpl == nil {
+			resp, err := client.BatchModifyPagePublishPageJs(siteIds, allPageIds, string(pagehtml), true, "")
+			if err != nil {
+				log.Fatal(err)
+			}
+			log.Println("taskId", resp.Data.TaskId)
+
+			if err := archiveUpload(client, pagehtml, source, resp.Data.TaskId, siteIds, allPageIds, pageSites); err != nil {
+				log.Println("warning: archive upload:", err)
+			}
+		} else {
+			// 每个页面的内容不同，逐个修改并发布
+			for _, pageId := range allPageIds {
+				siteId := pageSites[pageId]
+				if _, err := client.ModifyPageJs(siteId, strconv.Itoa(pageId), string(contents[pageId]), false); err != nil {
+					log.Fatal(err)
+				}
+				if _, err := client.PublishPage(siteId, pageId); err != nil {
+					log.Fatal(err)
+				}
+				log.Println("upload page", pageId, "to site", siteId)
+
+				if err := archiveUpload(client, contents[pageId], source, "", siteIds, []int{pageId}, pageSites); err != nil {
+					log.Println("warning: archive upload:", err)
+				}
+			}
+		}
+
+		if len(pageIds) > 0 {
+			for _, pageId := range allPageIds {
+				recordContent([]int{pageId}, contentHash(contents[pageId]))
+			}
+		} else {
+			var created []*stateResource
+			for _, pageId := range allPageIds {
+				created = append(created, newResource(resourcePage, "upload", pageSites[pageId], pageId, pageName, contentHash(contents[pageId])))
+			}
+			recordResources(created...)
 		}
-		log.Println("taskId", resp.Data.TaskId)
 	},
 }
 
@@ -238,6 +288,7 @@ var updatePageCmd = &cobra.Command{
 				log.Fatal(err)
 			}
 		}
+		recordChange(resourcePage, pageIds, func(r *stateResource) { r.Name = pageName })
 	},
 }
 
@@ -268,6 +319,7 @@ var upgradeSiteCmd = &cobra.Command{
 				log.Fatal(err)
 			}
 		}
+		recordChange(resourceSite, siteIds, func(r *stateResource) { r.Package = businessType })
 	},
 }
 
@@ -284,6 +336,7 @@ var changeDomainCmd = &cobra.Command{
 				log.Fatal(err)
 			}
 			log.Println("change domain", domain)
+			recordChange(resourceSite, []int{siteId}, func(r *stateResource) { r.Domain = domain })
 		}
 	},
 }
@@ -300,6 +353,7 @@ var updateSiteInfoCmd = &cobra.Command{
 				log.Fatal(err)
 			}
 		}
+		recordChange(resourceSite, siteIds, func(r *stateResource) { r.Name = siteName })
 	},
 }
 
@@ -358,6 +412,8 @@ var (
 	pageIds        []int  // 页面ID
 	onlySite       bool   // 是否只显示站点
 	taskId         string // 任务ID
+	uploadDiff     bool   // 显示与上次上传内容的差异，不上传
+	skipUnchanged  bool   // 跳过内容未变化的页面
 	pageId         int    // 页面ID
 	siteId         int    // 站点ID
 	prefix         string // 前缀
@@ -366,7 +422,10 @@ var (
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "debug")
-	rootCmd.PersistentFlags().StringVarP(&profile, "profile", "c", "default", "profile")
+	rootCmd.PersistentFlags().StringVarP(&profile, "profile", "c", "", "profile，默认使用配置文件中的 current_profile 或 default")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "配置文件路径，默认查找 ./kuanzhan.yaml 和 $HOME/.kuanzhan/kuanzhan.yaml")
+	rootCmd.PersistentFlags().StringVar(&appKey, "app-key", "", "快站APPKEY，优先于环境变量和配置文件")
+	rootCmd.PersistentFlags().StringVar(&appSecret, "app-secret", "", "快站APPSECRET，优先于环境变量和配置文件")
 
 	siteListCmd.PersistentFlags().BoolVarP(&onlySite, "only-site", "o", false, "是否只显示站点")
 
@@ -387,12 +446,15 @@ func init() {
 
 	uploadSiteCmd.PersistentFlags().IntVarP(&pageSize, "page", "p", 1, "创建页面数量")
 	uploadSiteCmd.PersistentFlags().StringVarP(&createPateTpl, "tpl", "t", "WHITE", "创建页面模板")
-	uploadSiteCmd.PersistentFlags().StringVarP(&pageName, "name", "n", "", "创建页面名称")
+	uploadSiteCmd.PersistentFlags().StringVarP(&pageName, "name", "n", "", "创建页面名称，--keep-head 时默认使用源页面的 <title>")
 	uploadSiteCmd.PersistentFlags().IntSliceVarP(&pageIds, "page-ids", "g", []int{}, "指定页面ID")
 	uploadSiteCmd.PersistentFlags().StringVarP(&taskId, "task-id", "a", "", "任务ID")
 	uploadSiteCmd.PersistentFlags().StringVarP(&localPath, "local-path", "l", "", "本地路径")
-
-	uploadSiteCmd.MarkPersistentFlagRequired("name")
+	uploadSiteCmd.PersistentFlags().BoolVar(&uploadDiff, "diff", false, "显示 --page-ids 中每个页面与上次上传内容的差异，不上传")
+	uploadSiteCmd.PersistentFlags().StringVar(&renderMode, "render", "", "将页面内容作为 Go 模板渲染: text|html")
+	uploadSiteCmd.PersistentFlags().StringVar(&valuesFile, "values", "", "模板变量文件（YAML），sites.<站点ID> 下为站点专用变量")
+	uploadSiteCmd.PersistentFlags().StringArrayVar(&setValues, "set", nil, "模板变量 key=value，可以指定多次")
+	uploadSiteCmd.PersistentFlags().BoolVar(&skipUnchanged, "skip-unchanged", false, "跳过内容与上次上传相同的页面")
 
 	updatePageCmd.PersistentFlags().StringVarP(&pageName, "name", "n", "", "更新页面名称")
 	updatePageCmd.MarkPersistentFlagRequired("name")
@@ -421,19 +483,26 @@ func init() {
 	publishPageCmd.PersistentFlags().IntVarP(&pageId, "page-id", "p", 0, "页面ID")
 	publishPageCmd.MarkPersistentFlagRequired("page-id")
 
-	initConfig()
+	cobra.OnInitialize(initConfig)
 }
 
 func initConfig() {
-	viper.SetConfigName("kuanzhan")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath("$HOME/.kuanzhan")
-	viper.AddConfigPath(".")
+	if cfgFile != "" {
+		viper.SetConfigFile(cfgFile)
+	} else {
+		viper.SetConfigName("kuanzhan")
+		viper.SetConfigType("yaml")
+		viper.AddConfigPath("$HOME/.kuanzhan")
+		viper.AddConfigPath(".")
+	}
 	viper.ReadInConfig()
 
-	appKey = viper.GetString("app_key")
-	appSecret = viper.GetString("app_secret")
-
+	if profile == "" {
+		profile = viper.GetString("current_profile")
+	}
+	if profile == "" {
+		profile = "default"
+	}
 }
 
 func main() {
@@ -447,66 +516,21 @@ func main() {
 }
 
 func newClient() *kuanzhan.Client {
-	if profile != "default" {
-		appKey = viper.GetString(fmt.Sprintf("profiles.%s.app_key", profile))
-		appSecret = viper.GetString(fmt.Sprintf("profiles.%s.app_secret", profile))
+	if multiProfile() {
+		log.Fatalf("--profile %s is only supported by read-only commands", profile)
 	}
-
-	client := kuanzhan.NewClient(appKey, appSecret)
-	client.SetDebug(debug)
-	return client
-}
-
-func downloadPage(url string) ([]byte, error) {
-	resp, err := http.Get(url)
+	creds, err := credentialProvider().Retrieve()
 	if err != nil {
-		return nil, err
+		log.Fatal(err)
 	}
-	defer resp.Body.Close()
-	doc, err := html.Parse(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	// 查找body节点
-	bodyNode := findBodyNode(doc)
-	if bodyNode == nil {
-		return nil, fmt.Errorf("body node not found")
-	}
-
-	// 将body节点内部内容转换为HTML字符串
-	body := renderBodyContent(bodyNode)
-	body = strings.TrimSpace(body)
-	return []byte(body), nil
-}
-
-// 查找body节点
-func findBodyNode(n *html.Node) *html.Node {
-	if n.Type == html.ElementNode && n.Data == "body" {
-		return n
-	}
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		if result := findBodyNode(c); result != nil {
-			return result
-		}
+	if debug {
+		log.Println("app_key from", creds.AppKeySource, "app_secret from", creds.AppSecretSource)
 	}
-	return nil
-}
-
-// 将节点渲染为HTML字符串
-func renderNode(n *html.Node) string {
-	var buf bytes.Buffer
-	html.Render(&buf, n)
-	return buf.String()
-}
 
-// 将body节点内部内容渲染为HTML字符串（不包含body标签本身）
-func renderBodyContent(bodyNode *html.Node) string {
-	var buf bytes.Buffer
-	for c := bodyNode.FirstChild; c != nil; c = c.NextSibling {
-		html.Render(&buf, c)
-	}
-	return buf.String()
+	client := kuanzhan.NewClient(creds.AppKey, creds.AppSecret)
+	client.SetDebug(debug)
+	client.SetRateLimit(rateLimit, 1)
+	return client
 }
 
 func randomUniqueDomain() string {
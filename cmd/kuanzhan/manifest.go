@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// manifest 期望状态清单，描述一组站点及其页面
+type manifest struct {
+	Sites []*manifestSite `yaml:"sites"`
+
+	path string // 清单文件路径，页面 source 的相对路径基于清单所在目录
+}
+
+// manifestSite 清单中的站点，按名称（或 id）与账号中的站点对应
+type manifestSite struct {
+	Name    string          `yaml:"name"`
+	ID      int             `yaml:"id,omitempty"`
+	Type    string          `yaml:"type,omitempty"`    // CreateSite 的站点类型，默认 FAST
+	Package string          `yaml:"package,omitempty"` // 套餐类型，如 SITE_EXCLUSIVE_YEAR
+	Domain  string          `yaml:"domain,omitempty"`  // 域名模式，* 在创建或更换域名时替换为随机字符
+	Pages   []*manifestPage `yaml:"pages,omitempty"`
+}
+
+// manifestPage 清单中的页面，按名称（或 id）与站点中的页面对应
+type manifestPage struct {
+	Name    string `yaml:"name"`
+	ID      int    `yaml:"id,omitempty"`
+	Tpl     string `yaml:"tpl,omitempty"`     // 创建页面的模板，默认 WHITE
+	Source  string `yaml:"source,omitempty"`  // 页面内容：本地文件或 URL
+	Content string `yaml:"content,omitempty"` // 页面内容：直接写在清单中
+
+	content []byte // 加载后的页面内容
+}
+
+// loadManifest 读取并校验清单，加载页面内容
+func loadManifest(file string) (*manifest, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &manifest{path: file}
+	if err := yaml.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", file, err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	for _, site := range m.Sites {
+		for _, page := range site.Pages {
+			if page.content, err = m.loadContent(page); err != nil {
+				return nil, fmt.Errorf("%s: site %q page %q: %w", file, site.Name, page.Name, err)
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m *manifest) validate() error {
+	if len(m.Sites) == 0 {
+		return fmt.Errorf("no sites")
+	}
+	siteNames := make(map[string]bool)
+	for i, site := range m.Sites {
+		if site.Name == "" {
+			return fmt.Errorf("sites[%d]: name is required", i)
+		}
+		if siteNames[site.Name] {
+			return fmt.Errorf("duplicate site %q", site.Name)
+		}
+		siteNames[site.Name] = true
+		if site.Type == "" {
+			site.Type = "FAST"
+		}
+		if strings.Count(site.Domain, "*") > 1 || strings.ContainsAny(site.Domain, "?[]\\") {
+			return fmt.Errorf("site %q: domain pattern may only contain a single * wildcard", site.Name)
+		}
+
+		pageNames := make(map[string]bool)
+		for j, page := range site.Pages {
+			if page.Name == "" {
+				return fmt.Errorf("site %q pages[%d]: name is required", site.Name, j)
+			}
+			if pageNames[page.Name] {
+				return fmt.Errorf("site %q: duplicate page %q", site.Name, page.Name)
+			}
+			pageNames[page.Name] = true
+			if page.Source != "" && page.Content != "" {
+				return fmt.Errorf("site %q page %q: source and content are mutually exclusive", site.Name, page.Name)
+			}
+			if page.Tpl == "" {
+				page.Tpl = "WHITE"
+			}
+		}
+	}
+	return nil
+}
+
+// loadContent 页面内容，没有配置 source 和 content 时返回 nil，表示不管理内容
+func (m *manifest) loadContent(page *manifestPage) ([]byte, error) {
+	switch {
+	case page.Content != "":
+		return []byte(page.Content), nil
+	case page.Source == "":
+		return nil, nil
+	case strings.HasPrefix(page.Source, "http://") || strings.HasPrefix(page.Source, "https://"):
+		imported, err := fetchPage(page.Source, importOpts)
+		if err != nil {
+			return nil, err
+		}
+		return imported.Content, nil
+	}
+
+	source := page.Source
+	if !filepath.IsAbs(source) {
+		source = filepath.Join(filepath.Dir(m.path), source)
+	}
+	return readLocalPage(source, "")
+}
+
+// matchDomain 站点域名是否符合清单中的域名模式，没有配置模式时总是符合
+func (site *manifestSite) matchDomain(domain string) bool {
+	if site.Domain == "" {
+		return true
+	}
+	for _, d := range []string{domain, siteHost(domain)} {
+		if ok, _ := path.Match(site.Domain, d); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// newDomain 按域名模式生成域名，没有配置模式时使用随机域名
+func (site *manifestSite) newDomain() string {
+	if site.Domain == "" {
+		return randomUniqueDomain()
+	}
+	return strings.Replace(site.Domain, "*", randomUniqueDomain(), 1)
+}
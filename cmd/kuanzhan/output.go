@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v3"
+)
+
+// 输出格式
+const (
+	outputTable    = "table"
+	outputJSON     = "json"
+	outputYAML     = "yaml"
+	outputCSV      = "csv"
+	outputTSV      = "tsv"
+	outputTemplate = "template"
+)
+
+var (
+	outputFormat string // 输出格式
+	outputTmpl   string // template 格式使用的 Go 模板
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output", outputTable, "读命令的输出格式: table|json|yaml|csv|tsv|template")
+	rootCmd.PersistentFlags().StringVar(&outputTmpl, "template", "", "--output template 使用的 Go 模板，对每条记录执行，例如 '{{.siteId}} {{.siteDomain}}'")
+}
+
+// outputColumn 输出列
+type outputColumn struct {
+	Header string // 表格表头
+	Field  string // 稳定字段名，与 json/yaml/csv 的字段名一致
+}
+
+// renderOutput 按 --output 输出记录
+// records 为结构体切片，json/yaml tag 使用相同的稳定字段名
+func renderOutput(w io.Writer, columns []outputColumn, records any) error {
+	switch outputFormat {
+	case outputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(records)
+	case outputYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(records); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+
+	maps, err := recordMaps(records)
+	if err != nil {
+		return err
+	}
+
+	switch outputFormat {
+	case outputTable:
+		table := tablewriter.NewWriter(w)
+		header := make([]any, len(columns))
+		for i, c := range columns {
+			header[i] = c.Header
+		}
+		table.Header(header...)
+		for _, m := range maps {
+			table.Append(recordValues(columns, m))
+		}
+		return table.Render()
+	case outputCSV, outputTSV:
+		cw := csv.NewWriter(w)
+		if outputFormat == outputTSV {
+			cw.Comma = '\t'
+		}
+		fields := make([]string, len(columns))
+		for i, c := range columns {
+			fields[i] = c.Field
+		}
+		cw.Write(fields)
+		for _, m := range maps {
+			cw.Write(recordValues(columns, m))
+		}
+		cw.Flush()
+		return cw.Error()
+	case outputTemplate:
+		if outputTmpl == "" {
+			return fmt.Errorf("--output template requires --template")
+		}
+		tmpl, err := template.New("output").Option("missingkey=zero").Parse(outputTmpl)
+		if err != nil {
+			return err
+		}
+		for _, m := range maps {
+			if err := tmpl.Execute(w, m); err != nil {
+				return err
+			}
+			fmt.Fprintln(w)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q", outputFormat)
+}
+
+// recordMaps 将记录转换为以 json 字段名为键的 map，保留数字原样
+func recordMaps(records any) ([]map[string]any, error) {
+	b, err := json.Marshal(records)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var maps []map[string]any
+	if err := dec.Decode(&maps); err != nil {
+		return nil, err
+	}
+	return maps, nil
+}
+
+func recordValues(columns []outputColumn, m map[string]any) []string {
+	values := make([]string, len(columns))
+	for i, c := range columns {
+		if v, ok := m[c.Field]; ok && v != nil {
+			values[i] = fmt.Sprint(v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var testRows = []siteRow{
+	{SiteID: 1, SiteName: "站点,一", SiteDomain: "a.kuaizhan.com", SiteStatus: "ONLINE", PackageRemainingDays: 30},
+	{SiteID: 2, SiteName: "站点二", SiteDomain: "b.kuaizhan.com", SiteStatus: "BANNED"},
+}
+
+func renderTestOutput(t *testing.T, format, tmpl string) string {
+	outputFormat, outputTmpl = format, tmpl
+	t.Cleanup(func() { outputFormat, outputTmpl = outputTable, "" })
+
+	var buf bytes.Buffer
+	if err := renderOutput(&buf, siteColumns, testRows); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestRenderOutput(t *testing.T) {
+	csv := renderTestOutput(t, outputCSV, "")
+	want := "siteId,siteName,siteDomain,packageName,siteStatus\n1,\"站点,一\",a.kuaizhan.com,,ONLINE\n2,站点二,b.kuaizhan.com,,BANNED\n"
+	if csv != want {
+		t.Errorf("csv:\n%s\nwant:\n%s", csv, want)
+	}
+
+	tsv := renderTestOutput(t, outputTSV, "")
+	if !strings.HasPrefix(tsv, "siteId\tsiteName\t") {
+		t.Errorf("tsv:\n%s", tsv)
+	}
+
+	var decoded []map[string]any
+	if err := json.Unmarshal([]byte(renderTestOutput(t, outputJSON, "")), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[0]["siteDomain"] != "a.kuaizhan.com" || decoded[0]["packageRemainingDays"] != float64(30) {
+		t.Errorf("json: %v", decoded)
+	}
+	// 空值也输出，每条记录的字段相同
+	for _, key := range []string{"account", "packageName", "pageId", "pageUrl", "error", "fetchedAt"} {
+		if _, ok := decoded[1][key]; !ok {
+			t.Errorf("json record missing %s: %v", key, decoded[1])
+		}
+	}
+
+	yaml := renderTestOutput(t, outputYAML, "")
+	if !strings.Contains(yaml, "  siteId: 1\n  siteName: 站点,一\n") || !strings.Contains(yaml, "  packageName: \"\"\n") {
+		t.Errorf("yaml:\n%s", yaml)
+	}
+
+	text := renderTestOutput(t, outputTemplate, "{{.siteId}} {{.siteStatus}}")
+	if text != "1 ONLINE\n2 BANNED\n" {
+		t.Errorf("template:\n%s", text)
+	}
+}
+
+func TestRenderOutputInvalid(t *testing.T) {
+	outputFormat = "xml"
+	defer func() { outputFormat = outputTable }()
+	if err := renderOutput(&bytes.Buffer{}, siteColumns, testRows); err == nil {
+		t.Error("expected error for unknown format")
+	}
+
+	outputFormat = outputTemplate
+	if err := renderOutput(&bytes.Buffer{}, siteColumns, testRows); err == nil {
+		t.Error("expected error for missing template")
+	}
+}
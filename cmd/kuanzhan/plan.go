@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"pkg.blksails.net/kuanzhan"
+)
+
+// 计划中的操作
+const (
+	actionCreateSite    = "create-site"    // CreateSite + PublishSite
+	actionRenameSite    = "rename-site"    // UpdateSiteInfo
+	actionChangeDomain  = "change-domain"  // ChangeDomain
+	actionOpenPackage   = "open-package"   // OpenBusinessPackage
+	actionCreatePage    = "create-page"    // CreateSitePage + UpdatePageName
+	actionRenamePage    = "rename-page"    // UpdatePageName
+	actionUpdateContent = "update-content" // ModifyPageJs + PublishPage
+)
+
+var (
+	manifestFile string // 清单文件
+	autoApprove  bool   // apply 不需要确认
+)
+
+// planAction 使账号与清单一致需要执行的一个操作
+type planAction struct {
+	Site   string `json:"site" yaml:"site"`
+	SiteID int    `json:"siteId,omitempty" yaml:"siteId,omitempty"`
+	Page   string `json:"page,omitempty" yaml:"page,omitempty"`
+	PageID int    `json:"pageId,omitempty" yaml:"pageId,omitempty"`
+	Action string `json:"action" yaml:"action"`
+	Detail string `json:"detail,omitempty" yaml:"detail,omitempty"`
+
+	site *manifestSite
+	page *manifestPage
+}
+
+var planColumns = []outputColumn{
+	{"站点", "site"},
+	{"站点ID", "siteId"},
+	{"页面", "page"},
+	{"页面ID", "pageId"},
+	{"操作", "action"},
+	{"详情", "detail"},
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "比较清单与账号",
+	Long:  "比较期望状态清单（YAML）与账号中的站点和页面，列出 apply 将要执行的操作，不做任何修改",
+	Run: func(cmd *cobra.Command, args []string) {
+		client := newClient()
+		m, actions := loadPlan(client)
+		printPlan(m, actions)
+	},
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "按清单创建和更新站点",
+	Long: `比较期望状态清单（YAML）与账号，创建缺少的站点和页面，更新站点名称、域名、套餐、页面名称和页面内容，使账号与清单一致。
+执行前显示计划并确认，--yes 跳过确认。遇到错误时停止，已执行的操作不会回滚。`,
+	Run: func(cmd *cobra.Command, args []string) {
+		client := newClient()
+		m, actions := loadPlan(client)
+		printPlan(m, actions)
+		if len(actions) == 0 {
+			return
+		}
+		if !autoApprove && !confirm("apply these changes?") {
+			log.Fatal("apply cancelled")
+		}
+
+		inv, err := openInventory(inventoryPath())
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer inv.Close()
+
+		a := newApplier(client, inv, m)
+		for i, action := range actions {
+			if err := a.apply(action); err != nil {
+				log.Fatalf("%s %s %s: %v (%d of %d actions applied)", action.Action, action.Site, action.Page, err, i, len(actions))
+			}
+		}
+		log.Printf("apply complete: %d actions", len(actions))
+	},
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{planCmd, applyCmd} {
+		cmd.Flags().StringVarP(&manifestFile, "file", "f", "", "期望状态清单文件（YAML）")
+		cmd.MarkFlagRequired("file")
+		rootCmd.AddCommand(cmd)
+	}
+	applyCmd.Flags().BoolVarP(&autoApprove, "yes", "y", false, "不确认直接执行")
+}
+
+// loadPlan 读取清单和账号当前状态，生成计划
+func loadPlan(client *kuanzhan.Client) (*manifest, []*planAction) {
+	m, err := loadManifest(manifestFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	resp, err := client.GetSiteIds()
+	if err != nil {
+		log.Fatal(err)
+	}
+	sites := fetchSiteRecords(context.Background(), client, profile, resp.Data.SiteIds, 10, true)
+
+	hashes, err := uploadedHashes(profile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	actions, err := buildPlan(m, sites, hashes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return m, actions
+}
+
+// uploadedHashes 每个页面最近一次上传内容的哈希
+func uploadedHashes(account string) (map[int]string, error) {
+	inv, err := openInventory(inventoryPath())
+	if err != nil {
+		return nil, err
+	}
+	defer inv.Close()
+
+	uploads, err := inv.LatestUploads(account)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[int]string, len(uploads))
+	for _, upload := range uploads {
+		hashes[upload.PageID] = upload.Hash
+	}
+	return hashes, nil
+}
+
+func printPlan(m *manifest, actions []*planAction) {
+	if len(actions) == 0 {
+		log.Printf("no changes, account matches %s", m.path)
+		return
+	}
+	if err := renderOutput(os.Stdout, planColumns, actions); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("plan: %d actions", len(actions))
+}
+
+// buildPlan 比较清单与账号中的站点，生成使两者一致的操作
+// 站点和页面按 id 或名称对应；页面内容与内容归档中最近一次上传的哈希比较
+func buildPlan(m *manifest, sites []*siteRecord, hashes map[int]string) ([]*planAction, error) {
+	var actions []*planAction
+	for _, site := range m.Sites {
+		rec, err := matchSite(site, sites)
+		if err != nil {
+			return nil, err
+		}
+
+		add := func(page *manifestPage, pageId int, action, detail string) {
+			a := &planAction{Site: site.Name, Action: action, Detail: detail, site: site, page: page, PageID: pageId}
+			if rec != nil {
+				a.SiteID = rec.SiteID
+			}
+			if page != nil {
+				a.Page = page.Name
+			}
+			actions = append(actions, a)
+		}
+
+		if rec == nil {
+			add(nil, 0, actionCreateSite, fmt.Sprintf("type %s, domain %s", site.Type, cmp.Or(site.Domain, "random")))
+			if site.Package != "" {
+				add(nil, 0, actionOpenPackage, site.Package)
+			}
+			for _, page := range site.Pages {
+				add(page, 0, actionCreatePage, "tpl "+page.Tpl)
+				if page.content != nil {
+					add(page, 0, actionUpdateContent, shortHash(contentHash(page.content)))
+				}
+			}
+			continue
+		}
+
+		if rec.SiteName != site.Name {
+			add(nil, 0, actionRenameSite, rec.SiteName+" → "+site.Name)
+		}
+		if !site.matchDomain(rec.SiteDomain) {
+			add(nil, 0, actionChangeDomain, rec.SiteDomain+" → "+site.Domain)
+		}
+		// 只为没有有效套餐的站点开通套餐，避免重复购买
+		if site.Package != "" && rec.PackageRemainingDays <= 0 {
+			add(nil, 0, actionOpenPackage, site.Package)
+		}
+
+		for _, page := range site.Pages {
+			current, err := matchPage(site, page, rec)
+			if err != nil {
+				return nil, err
+			}
+			if current == nil {
+				add(page, 0, actionCreatePage, "tpl "+page.Tpl)
+				if page.content != nil {
+					add(page, 0, actionUpdateContent, shortHash(contentHash(page.content)))
+				}
+				continue
+			}
+			if current.Title != page.Name {
+				add(page, current.PageID, actionRenamePage, current.Title+" → "+page.Name)
+			}
+			if page.content == nil {
+				continue
+			}
+			if hash := contentHash(page.content); hashes[current.PageID] != hash {
+				add(page, current.PageID, actionUpdateContent, shortHash(hashes[current.PageID])+" → "+shortHash(hash))
+			}
+		}
+	}
+	return actions, nil
+}
+
+// matchSite 清单站点对应的账号站点，不存在时返回 nil
+func matchSite(site *manifestSite, sites []*siteRecord) (*siteRecord, error) {
+	var found *siteRecord
+	for _, rec := range sites {
+		if site.ID != 0 && rec.SiteID != site.ID || site.ID == 0 && rec.SiteName != site.Name {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("site %q matches sites %d and %d, set id in the manifest", site.Name, found.SiteID, rec.SiteID)
+		}
+		found = rec
+	}
+	if found == nil && site.ID != 0 {
+		return nil, fmt.Errorf("site %q: site %d not found in account", site.Name, site.ID)
+	}
+	// 有站点获取失败时无法确定站点是否存在，避免重复创建
+	if found == nil {
+		for _, rec := range sites {
+			if rec.Error != "" && rec.SiteName == "" {
+				return nil, fmt.Errorf("site %q: cannot tell whether it exists, site %d: %s", site.Name, rec.SiteID, rec.Error)
+			}
+		}
+	}
+	if found != nil && found.Error != "" {
+		return nil, fmt.Errorf("site %q: %s", site.Name, found.Error)
+	}
+	return found, nil
+}
+
+// matchPage 清单页面对应的站点页面，不存在时返回 nil
+func matchPage(site *manifestSite, page *manifestPage, rec *siteRecord) (*pageRecord, error) {
+	var found *pageRecord
+	for i, p := range rec.Pages {
+		if page.ID != 0 && p.PageID != page.ID || page.ID == 0 && p.Title != page.Name {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("site %q page %q matches pages %d and %d, set id in the manifest", site.Name, page.Name, found.PageID, p.PageID)
+		}
+		found = &rec.Pages[i]
+	}
+	if found == nil && page.ID != 0 {
+		return nil, fmt.Errorf("site %q page %q: page %d not found in site %d", site.Name, page.Name, page.ID, rec.SiteID)
+	}
+	return found, nil
+}
+
+func shortHash(hash string) string {
+	if hash == "" {
+		return "unknown"
+	}
+	return hash[:min(12, len(hash))]
+}
+
+// confirm 询问用户确认，输入 yes 或 y 时返回 true
+func confirm(prompt string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// applier 执行计划，记录新建站点和页面的ID供后续操作使用
+type applier struct {
+	client   *kuanzhan.Client
+	inv      *inventory
+	manifest *manifest
+	siteIds  map[*manifestSite]int
+	pageIds  map[*manifestPage]int
+}
+
+func newApplier(client *kuanzhan.Client, inv *inventory, m *manifest) *applier {
+	return &applier{
+		client:   client,
+		inv:      inv,
+		manifest: m,
+		siteIds:  make(map[*manifestSite]int),
+		pageIds:  make(map[*manifestPage]int),
+	}
+}
+
+func (a *applier) apply(action *planAction) error {
+	siteId := action.SiteID
+	if siteId == 0 {
+		siteId = a.siteIds[action.site]
+	}
+	pageId := action.PageID
+	if pageId == 0 && action.page != nil {
+		pageId = a.pageIds[action.page]
+	}
+
+	switch action.Action {
+	case actionCreateSite:
+		resp, err := a.client.CreateSite(action.site.Name, action.site.newDomain(), action.site.Type, true)
+		if err != nil {
+			return err
+		}
+		if siteId, err = strconv.Atoi(resp.Data.SiteID); err != nil {
+			return err
+		}
+		a.siteIds[action.site] = siteId
+		if _, err := a.client.PublishSite(siteId); err != nil {
+			return err
+		}
+		site := newResource(resourceSite, "apply", siteId, 0, action.site.Name, "")
+		site.Domain = normalizeDomain(resp.Data.SiteDomain)
+		recordResources(site)
+		log.Println("created site", siteId, resp.Data.SiteDomain)
+	case actionRenameSite:
+		if _, err := a.client.UpdateSiteInfo(int64(siteId), action.site.Name); err != nil {
+			return err
+		}
+		recordChange(resourceSite, []int{siteId}, func(r *stateResource) { r.Name = action.site.Name })
+		log.Println("renamed site", siteId)
+	case actionChangeDomain:
+		domain := action.site.newDomain()
+		if _, err := a.client.ChangeDomain(int64(siteId), domain, true); err != nil {
+			return err
+		}
+		recordChange(resourceSite, []int{siteId}, func(r *stateResource) { r.Domain = domain })
+		log.Println("changed domain of site", siteId, "to", domain)
+	case actionOpenPackage:
+		if _, err := a.client.OpenBusinessPackage(action.site.Package, int64(siteId), "", ""); err != nil {
+			return err
+		}
+		recordChange(resourceSite, []int{siteId}, func(r *stateResource) { r.Package = action.site.Package })
+		log.Println("opened package", action.site.Package, "for site", siteId)
+	case actionCreatePage:
+		resp, err := a.client.CreateSitePage(siteId, action.page.Tpl)
+		if err != nil {
+			return err
+		}
+		pageId = resp.Data.PageId
+		a.pageIds[action.page] = pageId
+		if _, err := a.client.UpdatePageName(pageId, action.page.Name); err != nil {
+			return err
+		}
+		recordResources(newResource(resourcePage, "apply", siteId, pageId, action.page.Name, ""))
+		log.Println("created page", pageId, "in site", siteId)
+	case actionRenamePage:
+		if _, err := a.client.UpdatePageName(pageId, action.page.Name); err != nil {
+			return err
+		}
+		recordChange(resourcePage, []int{pageId}, func(r *stateResource) { r.Name = action.page.Name })
+		log.Println("renamed page", pageId)
+	case actionUpdateContent:
+		content := action.page.content
+		if _, err := a.client.ModifyPageJs(siteId, strconv.Itoa(pageId), string(content), false); err != nil {
+			return err
+		}
+		if _, err := a.client.PublishPage(siteId, pageId); err != nil {
+			return err
+		}
+		if err := a.archive(siteId, pageId, action.page); err != nil {
+			log.Println("warning: archive upload:", err)
+		}
+		recordContent([]int{pageId}, contentHash(content))
+		log.Println("updated content of page", pageId)
+	default:
+		return fmt.Errorf("unknown action %q", action.Action)
+	}
+	return nil
+}
+
+// archive 记录页面内容，下次 plan 时用于比较
+func (a *applier) archive(siteId, pageId int, page *manifestPage) error {
+	hash, err := putContent(page.content)
+	if err != nil {
+		return err
+	}
+	source := page.Source
+	if source == "" {
+		source = a.manifest.path
+	}
+	return a.inv.PutUploads(&uploadRecord{
+		Account:    profile,
+		SiteID:     siteId,
+		PageID:     pageId,
+		Hash:       hash,
+		Source:     source,
+		UploadedAt: time.Now(),
+	})
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testManifestYAML = `
+sites:
+  - name: a
+    domain: "jk-*.shop"
+    pages:
+      - name: 首页
+        source: index.html
+      - name: 活动页
+        id: 11
+  - name: new
+    package: SITE_EXCLUSIVE_YEAR
+    domain: "jk-*.shop"
+    pages:
+      - name: 落地页
+        content: "<p>hello</p>"
+`
+
+func writeTestManifest(t *testing.T) string {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<p>index</p>"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "campaign.yaml")
+	if err := os.WriteFile(path, []byte(testManifestYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadManifest(t *testing.T) {
+	m, err := loadManifest(writeTestManifest(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Sites[0].Type != "FAST" || m.Sites[0].Pages[0].Tpl != "WHITE" {
+		t.Errorf("defaults not applied: %+v", m.Sites[0])
+	}
+	if string(m.Sites[0].Pages[0].content) != "<p>index</p>" || m.Sites[0].Pages[1].content != nil {
+		t.Errorf("content: %+v", m.Sites[0].Pages)
+	}
+
+	for _, bad := range []string{
+		"sites: []",
+		"sites:\n  - name: a\n  - name: a",
+		"sites:\n  - name: a\n    domain: 'a*b*.shop'",
+		"sites:\n  - name: a\n    pages:\n      - name: p\n        source: x\n        content: y",
+	} {
+		path := filepath.Join(t.TempDir(), "bad.yaml")
+		os.WriteFile(path, []byte(bad), 0600)
+		if _, err := loadManifest(path); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestPlanApply(t *testing.T) {
+	inventoryFile, profile = filepath.Join(t.TempDir(), "inventory.db"), "default"
+	t.Cleanup(func() { inventoryFile, profile = "", "" })
+
+	fake := &fakeKuanzhan{sites: map[int]*fakeSite{
+		1: {Name: "a", Domain: "a.kuaizhan.com", RemainingDays: 100, Pages: map[int]string{10: "首页", 11: "旧名称"}},
+	}}
+	client := newTestServer(t, fake.ServeHTTP)
+	m, err := loadManifest(writeTestManifest(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := func() []*planAction {
+		resp, err := client.GetSiteIds()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sites := fetchSiteRecords(context.Background(), client, profile, resp.Data.SiteIds, 2, true)
+		hashes, err := uploadedHashes(profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actions, err := buildPlan(m, sites, hashes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return actions
+	}
+
+	actions := plan()
+	want := []string{
+		actionChangeDomain, actionUpdateContent, actionRenamePage,
+		actionCreateSite, actionOpenPackage, actionCreatePage, actionUpdateContent,
+	}
+	if len(actions) != len(want) {
+		t.Fatalf("got %d actions: %+v", len(actions), actions)
+	}
+	for i, w := range want {
+		if actions[i].Action != w {
+			t.Errorf("action %d = %s, want %s", i, actions[i].Action, w)
+		}
+	}
+
+	inv, err := openInventory(inventoryPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newApplier(client, inv, m)
+	for _, action := range actions {
+		if err := a.apply(action); err != nil {
+			t.Fatalf("%s: %v", action.Action, err)
+		}
+	}
+	inv.Close()
+
+	if site := fake.sites[1]; site.Contents[10] != "<p>index</p>" || site.Pages[11] != "活动页" || site.Published[10] != 1 {
+		t.Errorf("site a: %+v", site)
+	}
+	created := fake.sites[a.siteIds[m.Sites[1]]]
+	if created == nil || created.Package != "SITE_EXCLUSIVE_YEAR" || len(created.Pages) != 1 {
+		t.Fatalf("created site: %+v", created)
+	}
+	if ok := m.Sites[1].matchDomain(created.Domain); !ok {
+		t.Errorf("created domain %s does not match pattern", created.Domain)
+	}
+
+	if actions := plan(); len(actions) != 0 {
+		for _, a := range actions {
+			t.Errorf("unexpected action after apply: %+v", *a)
+		}
+	}
+}
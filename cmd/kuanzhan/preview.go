@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	previewAddr   string   // preview 监听地址
+	previewDevice string   // preview 设备预设
+	previewWidth  int      // preview 视口宽度，覆盖设备预设
+	previewHeight int      // preview 视口高度，覆盖设备预设
+	previewData   pageData // preview 模板的内置变量
+)
+
+// viewport 预览的视口大小
+type viewport struct {
+	Width  int
+	Height int
+}
+
+// previewDevices --device 的预设
+var previewDevices = map[string]viewport{
+	"iphone":    {390, 844},
+	"iphone-se": {375, 667},
+	"android":   {360, 800},
+	"ipad":      {768, 1024},
+}
+
+var previewCmd = &cobra.Command{
+	Use:   "preview <file|url>",
+	Short: "本地预览页面",
+	Long: `按 upload 的流程（下载源页面、导入 body 和资源地址、渲染模板）生成页面内容，
+在本地的手机视口中预览，不访问快站。
+
+本地文件及 --values、--watch-path 变化时自动刷新；预览源站URL时每次刷新重新下载。`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		vp, ok := previewDevices[previewDevice]
+		if !ok {
+			log.Fatalf("unknown --device %q", previewDevice)
+		}
+		if previewWidth > 0 {
+			vp.Width = previewWidth
+		}
+		if previewHeight > 0 {
+			vp.Height = previewHeight
+		}
+
+		src := &previewSource{
+			Source: args[0],
+			Import: importOpts,
+			Render: renderMode,
+			Values: valuesFile,
+			Set:    setValues,
+			Data:   previewData,
+		}
+		s := newPreviewServer(src, vp)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if !src.remote() {
+			paths := append([]string{src.Source}, watchExtra...)
+			if valuesFile != "" {
+				paths = append(paths, valuesFile)
+			}
+			w, err := newPathWatcher(paths)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer w.Close()
+			go w.run(ctx, 100*time.Millisecond, func() {
+				log.Println("changed, reloading")
+				s.reload()
+			})
+		}
+
+		ln, err := net.Listen("tcp", previewAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		srv := &http.Server{Handler: s.handler()}
+		go func() {
+			<-ctx.Done()
+			srv.Close()
+		}()
+		log.Printf("preview %s at http://%s, press Ctrl+C to stop", src.Source, ln.Addr())
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	pflags := previewCmd.PersistentFlags()
+	pflags.StringVar(&previewAddr, "addr", "127.0.0.1:8080", "监听地址")
+	pflags.StringVar(&previewDevice, "device", "iphone", "设备预设: iphone|iphone-se|android|ipad")
+	pflags.IntVar(&previewWidth, "width", 0, "视口宽度，覆盖 --device")
+	pflags.IntVar(&previewHeight, "height", 0, "视口高度，覆盖 --device")
+	pflags.StringVar(&renderMode, "render", "", "将页面内容作为 Go 模板渲染: text|html")
+	pflags.StringVar(&valuesFile, "values", "", "模板变量文件（YAML），sites.<站点ID> 下为站点专用变量")
+	pflags.StringArrayVar(&setValues, "set", nil, "模板变量 key=value，可以指定多次")
+	pflags.IntVar(&previewData.SiteID, "site-id", 0, "模板变量 .SiteID，同时选择变量文件中的站点变量")
+	pflags.IntVar(&previewData.PageID, "page-id", 0, "模板变量 .PageID")
+	pflags.StringVar(&previewData.SiteDomain, "domain", "", "模板变量 .SiteDomain")
+	pflags.StringVarP(&previewData.PageName, "name", "n", "", "模板变量 .PageName，默认为源页面的 <title> 或文件名")
+	pflags.StringArrayVar(&watchExtra, "watch-path", nil, "额外监视的文件或目录，变化时刷新，可以指定多次")
+	addImportFlags(previewCmd)
+	rootCmd.AddCommand(previewCmd)
+}
+
+// previewSource 预览的页面来源和 upload 的处理选项
+type previewSource struct {
+	Source string // 本地文件或源站URL
+	Import importOptions
+	Render string
+	Values string
+	Set    []string
+	Data   pageData
+}
+
+func (p *previewSource) remote() bool {
+	return strings.HasPrefix(p.Source, "http://") || strings.HasPrefix(p.Source, "https://")
+}
+
+// build 按 upload 的流程生成上传的内容：源站URL导入 body 和资源地址，本地文件原样读取，然后渲染模板
+func (p *previewSource) build() ([]byte, error) {
+	data := p.Data
+	var content []byte
+	if p.remote() {
+		page, err := fetchPage(p.Source, p.Import)
+		if err != nil {
+			return nil, err
+		}
+		content = page.Content
+		if data.PageName == "" {
+			data.PageName = page.Title
+		}
+	} else {
+		b, err := readLocalPage(p.Source, p.Import.Charset)
+		if err != nil {
+			return nil, err
+		}
+		content = b
+		if data.PageName == "" {
+			data.PageName = strings.TrimSuffix(filepath.Base(p.Source), filepath.Ext(p.Source))
+		}
+	}
+
+	if p.Render == "" {
+		return content, nil
+	}
+	tmpl, err := newPageTemplate(p.Render, content, p.Values, p.Set)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.render(data)
+}
+
+// previewServer 在手机视口中预览页面，页面变化时通知浏览器刷新
+type previewServer struct {
+	source   *previewSource
+	viewport viewport
+
+	mu      sync.Mutex
+	clients map[chan struct{}]bool
+}
+
+func newPreviewServer(source *previewSource, vp viewport) *previewServer {
+	return &previewServer{source: source, viewport: vp, clients: make(map[chan struct{}]bool)}
+}
+
+// handler / 为手机视口外框，/content 为页面内容，/events 为刷新通知（Server-Sent Events）
+func (s *previewServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/{$}", s.serveFrame)
+	mux.HandleFunc("/content", s.serveContent)
+	mux.HandleFunc("/events", s.serveEvents)
+	return mux
+}
+
+// reload 通知所有浏览器刷新页面内容
+func (s *previewServer) reload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.clients {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+var previewFrame = htmltemplate.Must(htmltemplate.New("frame").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>预览 {{.Source}}</title>
+<style>
+body { margin: 0; min-height: 100vh; display: flex; flex-direction: column; align-items: center; background: #e9ecef; font: 13px sans-serif; color: #555; }
+header { margin: 16px 0 12px; }
+iframe { width: {{.Width}}px; height: {{.Height}}px; border: 12px solid #222; border-radius: 28px; background: #fff; box-shadow: 0 8px 24px rgba(0,0,0,.25); }
+</style>
+</head>
+<body>
+<header>{{.Source}} · {{.Width}}×{{.Height}}</header>
+<iframe id="page" src="/content"></iframe>
+<script>
+new EventSource("/events").onmessage = function () {
+  document.getElementById("page").contentWindow.location.reload();
+};
+</script>
+</body>
+</html>
+`))
+
+func (s *previewServer) serveFrame(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := previewFrame.Execute(w, map[string]any{
+		"Source": s.source.Source,
+		"Width":  s.viewport.Width,
+		"Height": s.viewport.Height,
+	})
+	if err != nil {
+		log.Println("preview:", err)
+	}
+}
+
+// serveContent 每次请求重新生成页面内容，body 内容放入带手机 viewport 的文档中
+func (s *previewServer) serveContent(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	content, err := s.source.build()
+	if err != nil {
+		log.Println("preview:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "<pre>%s</pre>", htmltemplate.HTMLEscapeString(err.Error()))
+		return
+	}
+	if isDocument(content) {
+		w.Write(content)
+		return
+	}
+	fmt.Fprint(w, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1, maximum-scale=1, user-scalable=no">
+</head>
+<body>
+`)
+	w.Write(content)
+	fmt.Fprint(w, "\n</body>\n</html>\n")
+}
+
+func (s *previewServer) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.clients[ch] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ch:
+			fmt.Fprint(w, "data: reload\n\n")
+			flusher.Flush()
+		}
+	}
+}
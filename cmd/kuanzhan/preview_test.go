@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreviewSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>活动页</title></head><body><p>{{.PageName}} {{.phone}}</p><img src="a.png"></body></html>`))
+	}))
+	defer srv.Close()
+
+	src := &previewSource{Source: srv.URL + "/p/index.html", Render: renderHTML, Set: []string{"phone=400"}}
+	content, err := src.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<p>活动页 400</p><img src="` + srv.URL + `/p/a.png"/>`; string(content) != want {
+		t.Errorf("remote:\n got %s\nwant %s", content, want)
+	}
+
+	// 本地文件原样上传，不导入 body
+	page := filepath.Join(t.TempDir(), "landing.html")
+	os.WriteFile(page, []byte(`<p>{{.PageName}} {{.SiteDomain}}</p>`), 0644)
+	src = &previewSource{Source: page, Render: renderText, Data: pageData{SiteDomain: "a.kuaizhan.com"}}
+	if content, err = src.build(); err != nil || string(content) != "<p>landing a.kuaizhan.com</p>" {
+		t.Errorf("local: %q, %v", content, err)
+	}
+
+	src.Render = ""
+	if content, err = src.build(); err != nil || string(content) != "<p>{{.PageName}} {{.SiteDomain}}</p>" {
+		t.Errorf("no render: %q, %v", content, err)
+	}
+}
+
+func TestPreviewServer(t *testing.T) {
+	page := filepath.Join(t.TempDir(), "landing.html")
+	os.WriteFile(page, []byte("<p>v1</p>"), 0644)
+
+	s := newPreviewServer(&previewSource{Source: page}, viewport{Width: 375, Height: 667})
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+
+	if _, body := get("/"); !strings.Contains(body, "width: 375px; height: 667px") || !strings.Contains(body, `src="/content"`) {
+		t.Errorf("frame:\n%s", body)
+	}
+	if _, body := get("/content"); !strings.Contains(body, `name="viewport"`) || !strings.Contains(body, "<p>v1</p>") {
+		t.Errorf("content:\n%s", body)
+	}
+
+	// 每次请求重新生成内容
+	os.WriteFile(page, []byte("<!DOCTYPE html><html><body><p>v2</p></body></html>"), 0644)
+	if _, body := get("/content"); body != "<!DOCTYPE html><html><body><p>v2</p></body></html>" {
+		t.Errorf("full document should be served as is:\n%s", body)
+	}
+	os.Remove(page)
+	if code, _ := get("/content"); code != http.StatusInternalServerError {
+		t.Errorf("missing file: status %d", code)
+	}
+	if code, _ := get("/nope"); code != http.StatusNotFound {
+		t.Errorf("unknown path: status %d", code)
+	}
+
+	resp, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	r := bufio.NewReader(resp.Body)
+	if line, _ := r.ReadString('\n'); line != ": connected\n" {
+		t.Fatalf("events: %q", line)
+	}
+	r.ReadString('\n')
+
+	done := make(chan string)
+	go func() {
+		line, _ := r.ReadString('\n')
+		done <- line
+	}()
+	s.reload()
+	select {
+	case line := <-done:
+		if line != "data: reload\n" {
+			t.Errorf("event: %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("no reload event")
+	}
+}
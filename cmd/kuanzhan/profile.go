@@ -0,0 +1,249 @@
+package main
+
+import (
+	"log"
+	"os"
+	"slices"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"pkg.blksails.net/kuanzhan"
+)
+
+// profileKeys profile 支持的配置项，按显示顺序排列
+var profileKeys = []string{
+	"app_key",
+	"app_secret",
+	"credential_process",
+	"business_type",
+	"page_tpl",
+	"domain_prefix",
+	"domain_suffix",
+}
+
+var (
+	credentialProcess   string // 凭证命令
+	defaultBusinessType string // 默认套餐类型
+	defaultPageTpl      string // 默认页面模板
+	defaultDomainPrefix string // 默认域名前缀
+	defaultDomainSuffix string // 默认域名后缀
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "profile 管理",
+	Long:  "添加、查看、切换和删除配置文件中的 profile",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var profileAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "添加或更新 profile",
+	Long:  "添加或更新 profile，凭证使用全局参数 --app-key/--app-secret 指定",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := configFilePath()
+		doc, err := loadConfigNode(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		secret := appSecret
+		if secret != "" && encryptOnSave {
+			if secret, err = encryptValue(secret); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		values := map[string]string{
+			"app_key":            appKey,
+			"app_secret":         secret,
+			"credential_process": credentialProcess,
+			"business_type":      defaultBusinessType,
+			"page_tpl":           defaultPageTpl,
+			"domain_prefix":      defaultDomainPrefix,
+			"domain_suffix":      defaultDomainSuffix,
+		}
+		ensureMapping(doc, []string{"profiles", args[0]})
+		for _, key := range profileKeys {
+			if values[key] != "" {
+				setNode(doc, []string{"profiles", args[0], key}, values[key])
+			}
+		}
+
+		if err := saveConfigNode(path, doc); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("saved profile", args[0], "in", path)
+	},
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "profile 列表",
+	Long:  "列出配置文件中的所有 profile，* 表示当前 profile",
+	Run: func(cmd *cobra.Command, args []string) {
+		doc, err := loadConfigNode(configFilePath())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.Header("当前", "名称", "app_key", "app_secret", "credential_process", "套餐类型", "页面模板", "域名前缀", "域名后缀")
+		for _, name := range profileNames(doc) {
+			config := profileConfig(doc, name)
+			current := ""
+			if name == profile {
+				current = "*"
+			}
+			table.Append(current, name, config["app_key"], displaySecret(config["app_secret"]), config["credential_process"],
+				config["business_type"], config["page_tpl"], config["domain_prefix"], config["domain_suffix"])
+		}
+		table.Render()
+	},
+}
+
+var profileShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "显示 profile",
+	Long:  "显示 profile 的配置，默认显示当前 profile",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := profile
+		if len(args) > 0 {
+			name = args[0]
+		}
+
+		doc, err := loadConfigNode(configFilePath())
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !hasProfile(doc, name) {
+			log.Fatalf("profile %s not found", name)
+		}
+
+		config := profileConfig(doc, name)
+		table := tablewriter.NewWriter(os.Stdout)
+		table.Header("配置项", "值")
+		table.Append("name", name)
+		for _, key := range profileKeys {
+			value := config[key]
+			if key == "app_secret" {
+				value = displaySecret(value)
+			}
+			table.Append(key, value)
+		}
+		table.Render()
+	},
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "切换当前 profile",
+	Long:  "设置配置文件中的 current_profile，未指定 --profile 时使用",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := configFilePath()
+		doc, err := loadConfigNode(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !hasProfile(doc, args[0]) {
+			log.Fatalf("profile %s not found", args[0])
+		}
+
+		setNode(doc, []string{"current_profile"}, args[0])
+		if err := saveConfigNode(path, doc); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("current profile", args[0])
+	},
+}
+
+var profileRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "删除 profile",
+	Long:  "从配置文件中删除 profile",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := configFilePath()
+		doc, err := loadConfigNode(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !deleteNode(doc, []string{"profiles", args[0]}) {
+			log.Fatalf("profile %s not found", args[0])
+		}
+		if current := lookupNode(doc, []string{"current_profile"}); current != nil && current.Value == args[0] {
+			deleteNode(doc, []string{"current_profile"})
+		}
+
+		if err := saveConfigNode(path, doc); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("removed profile", args[0])
+	},
+}
+
+func init() {
+	flags := profileAddCmd.Flags()
+	flags.StringVar(&credentialProcess, "credential-process", "", "获取凭证的外部命令")
+	flags.StringVar(&defaultBusinessType, "default-business-type", "", "默认套餐类型")
+	flags.StringVar(&defaultPageTpl, "default-page-tpl", "", "默认页面模板")
+	flags.StringVar(&defaultDomainPrefix, "default-domain-prefix", "", "默认域名前缀")
+	flags.StringVar(&defaultDomainSuffix, "default-domain-suffix", "", "默认域名后缀")
+	flags.BoolVar(&encryptOnSave, "encrypt", false, "加密 app_secret 后写入")
+
+	profileCmd.AddCommand(profileAddCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileShowCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileRemoveCmd)
+	configCmd.AddCommand(profileCmd)
+}
+
+// profileNames 配置文件中的 profile 名称，顶级配置了凭证时包含 default
+func profileNames(doc *yaml.Node) []string {
+	var names []string
+	if profiles := lookupNode(doc, []string{"profiles"}); profiles != nil && profiles.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(profiles.Content); i += 2 {
+			names = append(names, profiles.Content[i].Value)
+		}
+	}
+	if !hasNamedProfile(doc, "default") && (lookupNode(doc, []string{"app_key"}) != nil || lookupNode(doc, []string{"credential_process"}) != nil) {
+		names = append([]string{"default"}, names...)
+	}
+	return names
+}
+
+func hasNamedProfile(doc *yaml.Node, name string) bool {
+	return lookupNode(doc, []string{"profiles", name}) != nil
+}
+
+func hasProfile(doc *yaml.Node, name string) bool {
+	return slices.Contains(profileNames(doc), name)
+}
+
+// profileConfig profile 的配置项，default profile 缺失的配置项使用顶级配置
+func profileConfig(doc *yaml.Node, name string) map[string]string {
+	config := make(map[string]string)
+	for _, key := range profileKeys {
+		if n := lookupNode(doc, []string{"profiles", name, key}); n != nil {
+			config[key] = n.Value
+		} else if n := lookupNode(doc, []string{key}); n != nil && (name == "default" || key != "app_key" && key != "app_secret" && key != "credential_process") {
+			config[key] = n.Value
+		}
+	}
+	return config
+}
+
+// displaySecret 加密值只显示前缀，明文值打码
+func displaySecret(s string) string {
+	if kuanzhan.IsEncrypted(s) {
+		return "enc:***"
+	}
+	return maskSecret(s)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"maps"
+	"os"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+	"pkg.blksails.net/kuanzhan"
+)
+
+// 页面内容的模板引擎
+const (
+	renderText = "text" // text/template，不转义
+	renderHTML = "html" // html/template，按上下文转义变量
+)
+
+var (
+	renderMode string   // upload 将页面内容作为模板渲染
+	valuesFile string   // 模板变量文件
+	setValues  []string // --set 指定的模板变量
+)
+
+// pageTemplate 页面内容模板，每个页面使用自己的变量渲染
+type pageTemplate struct {
+	execute    func(w io.Writer, data any) error
+	values     map[string]any         // 变量文件中所有站点共用的变量
+	siteValues map[int]map[string]any // 变量文件 sites 下按站点ID覆盖的变量
+	set        map[string]any         // --set 指定的变量
+}
+
+// pageData 模板的内置变量
+type pageData struct {
+	SiteID     int
+	PageID     int
+	SiteDomain string
+	PageName   string
+}
+
+// newPageTemplate 解析页面模板和变量，缺少变量时渲染报错
+// 变量优先级：内置变量 > --set > 变量文件 sites.<siteId> > 变量文件顶层
+func newPageTemplate(mode string, source []byte, valuesFile string, set []string) (*pageTemplate, error) {
+	t := &pageTemplate{set: make(map[string]any, len(set))}
+	switch mode {
+	case renderText:
+		tmpl, err := template.New("page").Option("missingkey=error").Parse(string(source))
+		if err != nil {
+			return nil, err
+		}
+		t.execute = tmpl.Execute
+	case renderHTML:
+		tmpl, err := htmltemplate.New("page").Option("missingkey=error").Parse(string(source))
+		if err != nil {
+			return nil, err
+		}
+		t.execute = tmpl.Execute
+	default:
+		return nil, fmt.Errorf("invalid --render %q, must be text or html", mode)
+	}
+
+	if valuesFile != "" {
+		b, err := os.ReadFile(valuesFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(b, &t.values); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", valuesFile, err)
+		}
+		var sites struct {
+			Sites map[int]map[string]any `yaml:"sites"`
+		}
+		if err := yaml.Unmarshal(b, &sites); err != nil {
+			return nil, fmt.Errorf("parse %s: sites: %w", valuesFile, err)
+		}
+		delete(t.values, "sites")
+		t.siteValues = sites.Sites
+	}
+
+	for _, kv := range set {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid --set %q, must be key=value", kv)
+		}
+		t.set[k] = v
+	}
+	return t, nil
+}
+
+// render 使用页面的变量渲染模板
+func (t *pageTemplate) render(data pageData) ([]byte, error) {
+	values := maps.Clone(t.values)
+	if values == nil {
+		values = make(map[string]any)
+	}
+	maps.Copy(values, t.siteValues[data.SiteID])
+	maps.Copy(values, t.set)
+	values["SiteID"] = data.SiteID
+	values["PageID"] = data.PageID
+	values["SiteDomain"] = data.SiteDomain
+	values["PageName"] = data.PageName
+
+	var buf bytes.Buffer
+	if err := t.execute(&buf, values); err != nil {
+		return nil, fmt.Errorf("render page %d: %w", data.PageID, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderPages 为每个页面渲染内容，返回页面内容和页面所属站点
+func renderPages(client *kuanzhan.Client, t *pageTemplate, siteIds, pageIds []int, known map[int]int, name string) (map[int][]byte, map[int]int, error) {
+	inv, err := openInventory(inventoryPath())
+	if err != nil {
+		return nil, nil, err
+	}
+	pageSites := resolvePageSites(client, inv, siteIds, pageIds, known)
+	inv.Close()
+
+	domains := make(map[int]string)
+	contents := make(map[int][]byte, len(pageIds))
+	for _, pageId := range pageIds {
+		siteId := pageSites[pageId]
+		if siteId == 0 {
+			return nil, nil, fmt.Errorf("page %d does not belong to any of sites %v", pageId, siteIds)
+		}
+		if _, ok := domains[siteId]; !ok {
+			resp, err := client.GetSiteInfo(siteId)
+			if err != nil {
+				return nil, nil, fmt.Errorf("GetSiteInfo %d: %w", siteId, err)
+			}
+			domains[siteId] = resp.Data.SiteDomain
+		}
+
+		content, err := t.render(pageData{SiteID: siteId, PageID: pageId, SiteDomain: domains[siteId], PageName: name})
+		if err != nil {
+			return nil, nil, err
+		}
+		contents[pageId] = content
+	}
+	return contents, pageSites, nil
+}
+
+// pageContents 每个页面上传的内容，没有模板时所有页面使用相同的内容
+// 渲染模板时同时确定页面所属站点，返回的页面站点包含 known
+func pageContents(client *kuanzhan.Client, t *pageTemplate, content []byte, siteIds, pageIds []int, known map[int]int, name string) (map[int][]byte, map[int]int, error) {
+	if t == nil {
+		contents := make(map[int][]byte, len(pageIds))
+		for _, pageId := range pageIds {
+			contents[pageId] = content
+		}
+		return contents, known, nil
+	}
+	return renderPages(client, t, siteIds, pageIds, known, name)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderPages(t *testing.T) {
+	oldInventory := inventoryFile
+	t.Cleanup(func() { inventoryFile = oldInventory })
+	dir := t.TempDir()
+	inventoryFile = filepath.Join(dir, "inventory.db")
+
+	values := filepath.Join(dir, "values.yaml")
+	err := os.WriteFile(values, []byte("phone: 400-000\ntrackingId: UA-0\nsites:\n  2:\n    trackingId: UA-2\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeKuanzhan{sites: map[int]*fakeSite{
+		1: {Name: "a", Domain: "a.shop", Pages: map[int]string{10: "首页"}},
+		2: {Name: "b", Domain: "b.shop", Pages: map[int]string{20: "首页"}},
+	}}
+	client := newTestServer(t, fake.ServeHTTP)
+
+	source := []byte(`<link rel="canonical" href="https://{{.SiteDomain}}/{{.PageID}}"><p>{{.phone}} {{.trackingId}} {{.note}}</p>`)
+	tmpl, err := newPageTemplate(renderHTML, source, values, []string{"note=<b>", "phone=400-111"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents, pageSites, err := renderPages(client, tmpl, []int{1, 2}, []int{10, 20}, nil, "首页")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pageSites[10] != 1 || pageSites[20] != 2 {
+		t.Errorf("pageSites = %v", pageSites)
+	}
+	want := map[int]string{
+		10: `<link rel="canonical" href="https://a.shop/10"><p>400-111 UA-0 &lt;b&gt;</p>`,
+		20: `<link rel="canonical" href="https://b.shop/20"><p>400-111 UA-2 &lt;b&gt;</p>`,
+	}
+	for pageId, w := range want {
+		if got := string(contents[pageId]); got != w {
+			t.Errorf("page %d:\n got %s\nwant %s", pageId, got, w)
+		}
+	}
+
+	// 缺少变量时报错
+	tmpl, err = newPageTemplate(renderText, []byte("{{.missing}}"), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := renderPages(client, tmpl, []int{1}, []int{10}, nil, "首页"); err == nil {
+		t.Error("expected error for missing variable")
+	}
+
+	if _, err := newPageTemplate(renderText, nil, "", []string{"novalue"}); err == nil {
+		t.Error("expected error for invalid --set")
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"cmp"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+)
+
+// 搜索范围
+const (
+	searchName    = "name"    // 站点名称
+	searchDomain  = "domain"  // 站点域名
+	searchTitle   = "title"   // 页面名称
+	searchContent = "content" // 上传的页面内容
+)
+
+// snippetRunes 匹配内容前后保留的字符数
+const snippetRunes = 20
+
+var searchIn []string // 搜索范围
+
+// searchHit 搜索结果
+type searchHit struct {
+	Account string `json:"account,omitempty" yaml:"account,omitempty"`
+	SiteID  int    `json:"siteId" yaml:"siteId"`
+	PageID  int    `json:"pageId,omitempty" yaml:"pageId,omitempty"`
+	URL     string `json:"url" yaml:"url"`
+	Field   string `json:"field" yaml:"field"`
+	Snippet string `json:"snippet" yaml:"snippet"`
+}
+
+var searchColumns = []outputColumn{
+	{"站点ID", "siteId"},
+	{"页面ID", "pageId"},
+	{"URL", "url"},
+	{"匹配字段", "field"},
+	{"匹配内容", "snippet"},
+}
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "搜索站点和页面",
+	Long: `在本地清单和内容归档中搜索站点名称、站点域名、页面名称以及通过 upload 上传的页面内容，不区分大小写。
+站点和页面信息来自 kuanzhan sync，页面内容为每个页面最近一次上传的内容。`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, field := range searchIn {
+			if !slices.Contains([]string{searchName, searchDomain, searchTitle, searchContent}, field) {
+				log.Fatalf("invalid --in %q", field)
+			}
+		}
+		query := regexp.MustCompile("(?i)" + regexp.QuoteMeta(strings.Join(args, " ")))
+
+		inv, err := openInventory(inventoryPath())
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer inv.Close()
+
+		hits := []searchHit{}
+		for _, account := range inventoryAccounts() {
+			accountHits, err := searchAccount(inv, account, query, searchIn)
+			if err != nil {
+				log.Fatal(err)
+			}
+			for _, hit := range accountHits {
+				if multiProfile() {
+					hit.Account = account
+				}
+				hits = append(hits, hit)
+			}
+		}
+
+		columns := searchColumns
+		if multiProfile() {
+			columns = slices.Concat([]outputColumn{accountColumn}, columns)
+		}
+		if err := renderOutput(os.Stdout, columns, hits); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	searchCmd.Flags().StringSliceVar(&searchIn, "in", []string{searchName, searchDomain, searchTitle, searchContent}, "搜索范围: name,domain,title,content")
+	rootCmd.AddCommand(searchCmd)
+}
+
+// searchAccount 搜索账号的站点、页面和上传内容，结果按站点ID和页面ID排序
+func searchAccount(inv *inventory, account string, query *regexp.Regexp, in []string) ([]searchHit, error) {
+	records, err := inv.Sites(account)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := []searchHit{}
+	domains := make(map[int]string, len(records))
+	for _, rec := range records {
+		domains[rec.SiteID] = rec.SiteDomain
+		if slices.Contains(in, searchName) && query.MatchString(rec.SiteName) {
+			hits = append(hits, searchHit{SiteID: rec.SiteID, URL: rec.SiteDomain, Field: searchName, Snippet: rec.SiteName})
+		}
+		if slices.Contains(in, searchDomain) && query.MatchString(rec.SiteDomain) {
+			hits = append(hits, searchHit{SiteID: rec.SiteID, URL: rec.SiteDomain, Field: searchDomain, Snippet: rec.SiteDomain})
+		}
+		if !slices.Contains(in, searchTitle) {
+			continue
+		}
+		for _, page := range rec.Pages {
+			if query.MatchString(page.Title) {
+				hits = append(hits, searchHit{SiteID: rec.SiteID, PageID: page.PageID, URL: pageURL(rec.SiteDomain, page.PageID), Field: searchTitle, Snippet: page.Title})
+			}
+		}
+	}
+
+	if slices.Contains(in, searchContent) {
+		uploads, err := inv.LatestUploads(account)
+		if err != nil {
+			return nil, err
+		}
+		for _, upload := range uploads {
+			content, err := readContent(upload.Hash)
+			if err != nil {
+				log.Printf("page %d: %v", upload.PageID, err)
+				continue
+			}
+			loc := query.FindIndex(content)
+			if loc == nil {
+				continue
+			}
+			hit := searchHit{
+				SiteID:  upload.SiteID,
+				PageID:  upload.PageID,
+				Field:   searchContent,
+				Snippet: snippet(content, loc[0], loc[1]),
+			}
+			if domain := domains[upload.SiteID]; domain != "" {
+				hit.URL = pageURL(domain, upload.PageID)
+			}
+			hits = append(hits, hit)
+		}
+	}
+
+	slices.SortStableFunc(hits, func(a, b searchHit) int {
+		return cmp.Or(cmp.Compare(a.SiteID, b.SiteID), cmp.Compare(a.PageID, b.PageID))
+	})
+	return hits, nil
+}
+
+// snippet 匹配内容及其前后 snippetRunes 个字符，空白折叠为一个空格
+func snippet(content []byte, start, end int) string {
+	from := start
+	for i := 0; i < snippetRunes && from > 0; i++ {
+		_, size := utf8.DecodeLastRune(content[:from])
+		from -= size
+	}
+	to := end
+	for i := 0; i < snippetRunes && to < len(content); i++ {
+		_, size := utf8.DecodeRune(content[to:])
+		to += size
+	}
+
+	s := strings.Join(strings.Fields(string(content[from:to])), " ")
+	if from > 0 {
+		s = "…" + s
+	}
+	if to < len(content) {
+		s += "…"
+	}
+	return s
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestSearchAccount(t *testing.T) {
+	dir := t.TempDir()
+	inventoryFile = filepath.Join(dir, "inventory.db")
+	t.Cleanup(func() { inventoryFile = "" })
+
+	inv, err := openInventory(inventoryPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inv.Close()
+
+	err = inv.PutSites(
+		&siteRecord{Account: "prod", SiteID: 1, SiteName: "降血脂专题", SiteDomain: "a.kuaizhan.com", Pages: []pageRecord{{10, "首页"}}},
+		&siteRecord{Account: "prod", SiteID: 2, SiteName: "b", SiteDomain: "b.kuaizhan.com", Pages: []pageRecord{{20, "血脂落地页"}, {21, "其他"}}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old, err := putContent([]byte("<p>血脂 旧版文案</p>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := putContent([]byte("<html><body>\n<h1>三个月   调理血脂的方法</h1></body></html>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := putContent([]byte("<p>nothing here</p>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	err = inv.PutUploads(
+		&uploadRecord{Account: "prod", SiteID: 2, PageID: 21, Hash: old, UploadedAt: now.Add(-time.Hour)},
+		&uploadRecord{Account: "prod", SiteID: 2, PageID: 21, Hash: other, UploadedAt: now},
+		&uploadRecord{Account: "prod", SiteID: 1, PageID: 10, Hash: current, UploadedAt: now},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := regexp.MustCompile("(?i)" + regexp.QuoteMeta("血脂"))
+	hits, err := searchAccount(inv, "prod", query, []string{searchName, searchDomain, searchTitle, searchContent})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 页面 21 最近一次上传的内容不包含查询，不应出现
+	want := []struct {
+		siteId, pageId int
+		field          string
+	}{
+		{1, 0, searchName},
+		{1, 10, searchContent},
+		{2, 20, searchTitle},
+	}
+	if len(hits) != len(want) {
+		t.Fatalf("got %d hits: %+v", len(hits), hits)
+	}
+	for i, w := range want {
+		if hits[i].SiteID != w.siteId || hits[i].PageID != w.pageId || hits[i].Field != w.field {
+			t.Errorf("hit %d = %+v, want %+v", i, hits[i], w)
+		}
+	}
+	if hits[1].URL != "a.kuaizhan.com/10" || hits[1].Snippet != "…><body> <h1>三个月 调理血脂的方法</h1></body></htm…" {
+		t.Errorf("content hit: %+v", hits[1])
+	}
+
+	hits, err = searchAccount(inv, "prod", query, []string{searchTitle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].PageID != 20 {
+		t.Errorf("title only: %+v", hits)
+	}
+}
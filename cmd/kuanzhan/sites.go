@@ -0,0 +1,212 @@
+package main
+
+import (
+	"log"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"pkg.blksails.net/kuanzhan"
+)
+
+// siteRow 站点/页面记录，list、site-info、page-list 共用
+type siteRow struct {
+	Account              string `json:"account" yaml:"account"`
+	SiteID               int    `json:"siteId" yaml:"siteId"`
+	SiteName             string `json:"siteName" yaml:"siteName"`
+	SiteDomain           string `json:"siteDomain" yaml:"siteDomain"`
+	PackageName          string `json:"packageName" yaml:"packageName"`
+	SiteStatus           string `json:"siteStatus" yaml:"siteStatus"`
+	PackageRemainingDays int    `json:"packageRemainingDays" yaml:"packageRemainingDays"`
+	PageID               int    `json:"pageId" yaml:"pageId"`
+	PageName             string `json:"pageName" yaml:"pageName"`
+	PageURL              string `json:"pageUrl" yaml:"pageUrl"`
+	Error                string `json:"error" yaml:"error"`
+	FetchedAt            string `json:"fetchedAt" yaml:"fetchedAt"`
+}
+
+// newSiteRow 使用站点信息创建记录
+func newSiteRow(siteId int, info *kuanzhan.GetSiteInfoResponse) siteRow {
+	return siteRow{
+		SiteID:               siteId,
+		SiteName:             info.Data.SiteName,
+		SiteDomain:           info.Data.SiteDomain,
+		PackageName:          info.Data.PackageName,
+		SiteStatus:           info.Data.SiteStatus,
+		PackageRemainingDays: info.Data.PackageRemainingDays,
+	}
+}
+
+// withPage 返回带页面信息的记录副本
+func (r siteRow) withPage(pageId int, title string) siteRow {
+	r.PageID = pageId
+	r.PageName = title
+	r.PageURL = pageURL(r.SiteDomain, pageId)
+	return r
+}
+
+// pageURL 页面访问地址
+func pageURL(siteDomain string, pageId int) string {
+	return siteDomain + "/" + strconv.Itoa(pageId)
+}
+
+var (
+	accountColumn = outputColumn{"账号", "account"}
+	siteColumns   = []outputColumn{
+		{"站点ID", "siteId"},
+		{"站点名称", "siteName"},
+		{"站点URL", "siteDomain"},
+		{"套餐类型", "packageName"},
+		{"站点状态", "siteStatus"},
+	}
+	pageColumns = []outputColumn{
+		{"页面ID", "pageId"},
+		{"页面名称", "pageName"},
+		{"页面URL", "pageUrl"},
+	}
+	remainingDaysColumn = outputColumn{"套餐剩余天数", "packageRemainingDays"}
+	errorColumn         = outputColumn{"错误", "error"}
+	fetchedAtColumn     = outputColumn{"更新时间", "fetchedAt"}
+)
+
+// taskRow 批量上传任务中单个页面的状态
+type taskRow struct {
+	TaskID     string `json:"taskId" yaml:"taskId"`
+	TaskStatus string `json:"taskStatus" yaml:"taskStatus"`
+	SiteID     int    `json:"siteId" yaml:"siteId"`
+	PageID     int    `json:"pageId" yaml:"pageId"`
+	Status     string `json:"status" yaml:"status"`
+	ErrorMsg   string `json:"errorMsg" yaml:"errorMsg"`
+}
+
+var taskColumns = []outputColumn{
+	{"任务ID", "taskId"},
+	{"任务状态", "taskStatus"},
+	{"站点ID", "siteId"},
+	{"页面ID", "pageId"},
+	{"页面状态", "status"},
+	{"信息", "errorMsg"},
+}
+
+var siteInfoCmd = &cobra.Command{
+	Use:   "site-info",
+	Short: "站点信息",
+	Long:  "获取指定站点的名称、域名、套餐和状态",
+	Run: func(cmd *cobra.Command, args []string) {
+		columns := slices.Concat(siteColumns, []outputColumn{remainingDaysColumn})
+		rows := []siteRow{}
+		if useCached {
+			for _, rec := range cachedSites(siteIds) {
+				rows = append(rows, rec.siteRow())
+			}
+			columns = append(columns, fetchedAtColumn)
+		} else {
+			client := newClient()
+			for _, siteId := range siteIds {
+				info, err := client.GetSiteInfo(siteId)
+				if err != nil {
+					log.Fatal(err)
+				}
+				rows = append(rows, newSiteRow(siteId, info))
+			}
+		}
+
+		if err := renderOutput(os.Stdout, columns, rows); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var pageListCmd = &cobra.Command{
+	Use:   "page-list",
+	Short: "页面列表",
+	Long:  "获取指定站点的页面ID、名称和URL",
+	Run: func(cmd *cobra.Command, args []string) {
+		columns := slices.Concat([]outputColumn{{"站点ID", "siteId"}}, pageColumns)
+		rows := []siteRow{}
+		if useCached {
+			for _, rec := range cachedSites(siteIds) {
+				site := rec.siteRow()
+				for _, page := range rec.Pages {
+					rows = append(rows, site.withPage(page.PageID, page.Title))
+				}
+			}
+			columns = append(columns, fetchedAtColumn)
+		} else {
+			client := newClient()
+			for _, siteId := range siteIds {
+				info, err := client.GetSiteInfo(siteId)
+				if err != nil {
+					log.Fatal(err)
+				}
+				pages, err := client.GetPageName(siteId)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				site := siteRow{SiteID: siteId, SiteDomain: info.Data.SiteDomain}
+				for _, page := range pages.Data {
+					rows = append(rows, site.withPage(page.PageId, page.Title))
+				}
+			}
+		}
+
+		if err := renderOutput(os.Stdout, columns, rows); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var taskStatusCmd = &cobra.Command{
+	Use:   "task-status",
+	Short: "上传任务状态",
+	Long:  "查询批量上传任务中每个页面的状态",
+	Run: func(cmd *cobra.Command, args []string) {
+		client := newClient()
+		resp, err := client.BatchModifyPagePublishPageJs(nil, nil, "", true, taskId)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if resp.Data == nil {
+			log.Fatalf("task %s: %s", taskId, resp.Msg)
+		}
+
+		if err := renderOutput(os.Stdout, taskColumns, taskRows(taskId, resp.Data.Task)); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// taskRows 展开任务中所有页面的状态
+func taskRows(taskId string, task kuanzhan.Task) []taskRow {
+	rows := []taskRow{}
+	for _, pages := range [][]kuanzhan.SucceedPage{task.SucceedPages, task.WaitingPages, task.FailedPages} {
+		for _, page := range pages {
+			rows = append(rows, taskRow{
+				TaskID:     taskId,
+				TaskStatus: task.TaskStatus,
+				SiteID:     page.SiteId,
+				PageID:     page.PageID,
+				Status:     page.Status,
+				ErrorMsg:   page.ErrorMsg,
+			})
+		}
+	}
+	return rows
+}
+
+func init() {
+	siteInfoCmd.Flags().IntSliceVarP(&siteIds, "site-ids", "i", []int{}, "站点ID")
+	siteInfoCmd.MarkFlagRequired("site-ids")
+
+	pageListCmd.Flags().IntSliceVarP(&siteIds, "site-ids", "i", []int{}, "站点ID")
+	pageListCmd.MarkFlagRequired("site-ids")
+
+	taskStatusCmd.Flags().StringVarP(&taskId, "task-id", "a", "", "任务ID")
+	taskStatusCmd.MarkFlagRequired("task-id")
+
+	rootCmd.AddCommand(siteInfoCmd)
+	rootCmd.AddCommand(pageListCmd)
+	rootCmd.AddCommand(taskStatusCmd)
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// 状态文件中的资源类型
+const (
+	resourceSite = "site"
+	resourcePage = "page"
+)
+
+const (
+	stateVersion     = 1
+	stateLockTimeout = 10 * time.Second // 等待状态文件锁的时间
+	stateLockStale   = 10 * time.Minute // 超过该时间的锁视为残留
+)
+
+var (
+	stateFilePath string // 状态文件
+	campaign      string // 创建资源时记录的活动标签
+	stateType     string // state list 的资源类型
+	stateIdsOnly  bool   // state list 只输出ID
+)
+
+// stateResource 由命令行创建或导入的资源
+type stateResource struct {
+	Type        string    `json:"type" yaml:"type"`
+	Profile     string    `json:"profile" yaml:"profile"`
+	SiteID      int       `json:"siteId" yaml:"siteId"`
+	PageID      int       `json:"pageId,omitempty" yaml:"pageId,omitempty"`
+	Name        string    `json:"name,omitempty" yaml:"name,omitempty"`
+	Domain      string    `json:"domain,omitempty" yaml:"domain,omitempty"`
+	Package     string    `json:"package,omitempty" yaml:"package,omitempty"`
+	Campaign    string    `json:"campaign,omitempty" yaml:"campaign,omitempty"`
+	Command     string    `json:"command" yaml:"command"`
+	ContentHash string    `json:"contentHash,omitempty" yaml:"contentHash,omitempty"`
+	CreatedAt   time.Time `json:"createdAt" yaml:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt" yaml:"updatedAt"`
+}
+
+func (r *stateResource) same(o *stateResource) bool {
+	return r.Type == o.Type && r.Profile == o.Profile && r.SiteID == o.SiteID && r.PageID == o.PageID
+}
+
+// stateFile 状态文件内容
+type stateFile struct {
+	Version   int              `json:"version"`
+	Resources []*stateResource `json:"resources"`
+}
+
+// upsert 添加资源，已存在时更新名称、活动、内容哈希和更新时间
+func (s *stateFile) upsert(r *stateResource) {
+	i := slices.IndexFunc(s.Resources, r.same)
+	if i < 0 {
+		s.Resources = append(s.Resources, r)
+		return
+	}
+	existing := s.Resources[i]
+	existing.Name = cmp.Or(r.Name, existing.Name)
+	existing.Domain = cmp.Or(r.Domain, existing.Domain)
+	existing.Package = cmp.Or(r.Package, existing.Package)
+	existing.Campaign = cmp.Or(r.Campaign, existing.Campaign)
+	existing.ContentHash = cmp.Or(r.ContentHash, existing.ContentHash)
+	existing.UpdatedAt = r.UpdatedAt
+}
+
+// sort 按 profile、站点ID、页面ID排序，站点排在其页面之前
+func (s *stateFile) sort() {
+	slices.SortFunc(s.Resources, func(a, b *stateResource) int {
+		return cmp.Or(
+			strings.Compare(a.Profile, b.Profile),
+			cmp.Compare(a.SiteID, b.SiteID),
+			cmp.Compare(a.PageID, b.PageID),
+		)
+	})
+}
+
+// statePath 状态文件路径，默认与本地清单在同一目录
+func statePath() string {
+	if stateFilePath != "" {
+		return stateFilePath
+	}
+	return filepath.Join(filepath.Dir(inventoryPath()), "state.json")
+}
+
+// loadState 读取状态文件，文件不存在时返回空状态
+func loadState(path string) (*stateFile, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &stateFile{Version: stateVersion}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := &stateFile{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if s.Version > stateVersion {
+		return nil, fmt.Errorf("%s: unsupported state version %d", path, s.Version)
+	}
+	return s, nil
+}
+
+// saveState 原子写入状态文件
+func saveState(path string, s *stateFile) error {
+	s.Version = stateVersion
+	s.sort()
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// lockState 创建锁文件，返回释放锁的函数
+// 其他进程持有锁时等待 stateLockTimeout，超过 stateLockStale 的锁视为残留并删除
+func lockState(path string) (func(), error) {
+	lock := path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lock), 0700); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(stateLockTimeout)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > stateLockStale {
+			log.Printf("removing stale state lock %s", lock)
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			owner, _ := os.ReadFile(lock)
+			return nil, fmt.Errorf("state file %s is locked by process %s", path, strings.TrimSpace(string(owner)))
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// updateState 加锁读取状态文件，执行 fn 后写回
+func updateState(fn func(s *stateFile) error) error {
+	path := statePath()
+	unlock, err := lockState(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s, err := loadState(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(s); err != nil {
+		return err
+	}
+	return saveState(path, s)
+}
+
+// newResource 使用当前 profile 和 --campaign 创建资源记录
+func newResource(typ, command string, siteId, pageId int, name, hash string) *stateResource {
+	now := time.Now()
+	return &stateResource{
+		Type:        typ,
+		Profile:     profile,
+		SiteID:      siteId,
+		PageID:      pageId,
+		Name:        name,
+		Campaign:    campaign,
+		Command:     command,
+		ContentHash: hash,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// kuaizhanDomainSuffix 快站二级域名的后缀，change-domain 只指定后缀前的部分
+const kuaizhanDomainSuffix = ".kuaizhan.com"
+
+// normalizeDomain 去掉协议、路径和快站域名后缀，用于比较记录的域名和站点当前域名
+// GetSiteInfo 返回完整域名，change-domain 记录的是二级域名前缀，两者都规范化为前缀
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(siteHost(domain), kuaizhanDomainSuffix)
+}
+
+// recordResources 记录创建的资源，失败时只输出警告
+func recordResources(resources ...*stateResource) {
+	err := updateState(func(s *stateFile) error {
+		for _, r := range resources {
+			s.upsert(r)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println("warning: record state:", err)
+	}
+}
+
+// recordContent 更新状态文件中已记录页面的内容哈希，不添加新资源
+func recordContent(pageIds []int, hash string) {
+	recordChange(resourcePage, pageIds, func(r *stateResource) { r.ContentHash = hash })
+}
+
+// recordChange 修改状态文件中已记录的站点或页面，不添加新资源
+// 命令行修改站点名称、域名或页面名称后调用，使 drift 以新的值为准
+func recordChange(typ string, ids []int, fn func(r *stateResource)) {
+	err := updateState(func(s *stateFile) error {
+		now := time.Now()
+		for _, r := range s.Resources {
+			id := r.SiteID
+			if typ == resourcePage {
+				id = r.PageID
+			}
+			if r.Type == typ && r.Profile == profile && slices.Contains(ids, id) {
+				fn(r)
+				r.UpdatedAt = now
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println("warning: record state:", err)
+	}
+}
+
+var stateColumns = []outputColumn{
+	{"类型", "type"},
+	{"账号", "profile"},
+	{"站点ID", "siteId"},
+	{"页面ID", "pageId"},
+	{"名称", "name"},
+	{"活动", "campaign"},
+	{"命令", "command"},
+	{"创建时间", "createdAt"},
+}
+
+var stateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "命令行创建的资源",
+	Long: `管理状态文件（默认 ~/.kuanzhan/state.json）中记录的资源。
+create-site、upload、apply 创建的站点和页面会记录到状态文件，包括时间、profile、命令、--campaign 和内容哈希。`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var stateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出资源",
+	Long:  "列出状态文件中的资源，--campaign 只列出指定活动的资源，--ids 输出逗号分隔的ID供其他命令使用",
+	Run: func(cmd *cobra.Command, args []string) {
+		if stateType != "" && stateType != resourceSite && stateType != resourcePage {
+			log.Fatalf("invalid --type %q", stateType)
+		}
+		s, err := loadState(statePath())
+		if err != nil {
+			log.Fatal(err)
+		}
+		resources := filterResources(s.Resources, campaign, stateType)
+
+		if stateIdsOnly {
+			fmt.Println(resourceIds(resources, stateType))
+			return
+		}
+		if err := renderOutput(os.Stdout, stateColumns, resources); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var stateShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "显示资源",
+	Long:  "显示站点ID或页面ID对应的资源的完整记录，站点同时显示其页面",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		s, err := loadState(statePath())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var resources []*stateResource
+		for _, r := range s.Resources {
+			if r.SiteID == id || r.PageID == id {
+				resources = append(resources, r)
+			}
+		}
+		if len(resources) == 0 {
+			log.Fatalf("%d is not in state file %s", id, statePath())
+		}
+
+		// 默认输出完整记录
+		if !cmd.Flags().Changed("output") {
+			outputFormat = outputYAML
+		}
+		if err := renderOutput(os.Stdout, stateColumns, resources); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var stateImportCmd = &cobra.Command{
+	Use:   "import <siteId>...",
+	Short: "导入已有站点",
+	Long:  "将不是由命令行创建的站点及其页面记录到状态文件，--page-ids 只导入指定页面",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client := newClient()
+		var resources []*stateResource
+		for _, arg := range args {
+			siteId, err := strconv.Atoi(arg)
+			if err != nil {
+				log.Fatal(err)
+			}
+			rec := fetchSiteRecord(client, profile, siteId, true)
+			if rec.Error != "" {
+				log.Fatalf("site %d: %s", siteId, rec.Error)
+			}
+
+			site := newResource(resourceSite, "import", siteId, 0, rec.SiteName, "")
+			site.Domain = normalizeDomain(rec.SiteDomain)
+			resources = append(resources, site)
+			for _, page := range rec.Pages {
+				if len(pageIds) == 0 || slices.Contains(pageIds, page.PageID) {
+					resources = append(resources, newResource(resourcePage, "import", siteId, page.PageID, page.Title, ""))
+				}
+			}
+		}
+
+		err := updateState(func(s *stateFile) error {
+			for _, r := range resources {
+				s.upsert(r)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("imported %d resources into %s", len(resources), statePath())
+	},
+}
+
+var stateForgetCmd = &cobra.Command{
+	Use:   "forget [<id>...]",
+	Short: "移除资源记录",
+	Long:  "从状态文件中移除资源记录（不删除快站中的站点和页面）。站点ID同时移除其页面，--campaign 移除整个活动",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 && campaign == "" {
+			log.Fatal("specify ids or --campaign")
+		}
+		ids := make([]int, 0, len(args))
+		for _, arg := range args {
+			id, err := strconv.Atoi(arg)
+			if err != nil {
+				log.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+
+		var removed int
+		err := updateState(func(s *stateFile) error {
+			before := len(s.Resources)
+			s.Resources = slices.DeleteFunc(s.Resources, func(r *stateResource) bool {
+				if len(ids) == 0 {
+					return r.Campaign == campaign
+				}
+				return (slices.Contains(ids, r.SiteID) || slices.Contains(ids, r.PageID)) && (campaign == "" || r.Campaign == campaign)
+			})
+			removed = before - len(s.Resources)
+			return nil
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("forgot %d resources", removed)
+	},
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&stateFilePath, "state", "", "状态文件，默认 ~/.kuanzhan/state.json")
+	rootCmd.PersistentFlags().StringVar(&campaign, "campaign", "", "活动标签：创建资源时记录到状态文件，state 命令按活动筛选")
+
+	stateListCmd.Flags().StringVar(&stateType, "type", "", "只列出指定类型的资源: site|page")
+	stateListCmd.Flags().BoolVar(&stateIdsOnly, "ids", false, "只输出逗号分隔的ID（--type page 时为页面ID，否则为站点ID）")
+	stateImportCmd.Flags().IntSliceVarP(&pageIds, "page-ids", "g", []int{}, "只导入指定页面")
+
+	stateCmd.AddCommand(stateListCmd, stateShowCmd, stateImportCmd, stateForgetCmd)
+	rootCmd.AddCommand(stateCmd)
+}
+
+// filterResources 按活动和类型筛选资源，参数为空时不筛选
+func filterResources(resources []*stateResource, campaign, typ string) []*stateResource {
+	filtered := []*stateResource{}
+	for _, r := range resources {
+		if campaign != "" && r.Campaign != campaign || typ != "" && r.Type != typ {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
+// resourceIds 逗号分隔的去重ID，typ 为 page 时为页面ID，否则为站点ID
+func resourceIds(resources []*stateResource, typ string) string {
+	var ids []string
+	for _, r := range resources {
+		id := r.SiteID
+		if typ == resourcePage {
+			id = r.PageID
+		}
+		if s := strconv.Itoa(id); id != 0 && !slices.Contains(ids, s) {
+			ids = append(ids, s)
+		}
+	}
+	return strings.Join(ids, ",")
+}
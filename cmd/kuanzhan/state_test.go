@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUpdateState(t *testing.T) {
+	oldPath, oldProfile, oldCampaign := stateFilePath, profile, campaign
+	t.Cleanup(func() { stateFilePath, profile, campaign = oldPath, oldProfile, oldCampaign })
+	stateFilePath = filepath.Join(t.TempDir(), "state.json")
+	profile = "default"
+
+	campaign = "spring"
+	recordResources(
+		newResource(resourcePage, "upload", 1, 11, "首页", "aaa"),
+		newResource(resourceSite, "create-site", 1, 0, "a", ""),
+	)
+	campaign = ""
+	recordResources(newResource(resourceSite, "create-site", 2, 0, "b", ""))
+	recordContent([]int{11, 99}, "bbb")
+
+	s, err := loadState(stateFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Resources) != 3 {
+		t.Fatalf("resources: %+v", s.Resources)
+	}
+	// 站点排在其页面之前，未记录的页面 99 不会被添加
+	if s.Resources[0].SiteID != 1 || s.Resources[0].Type != resourceSite || s.Resources[1].PageID != 11 || s.Resources[2].SiteID != 2 {
+		t.Errorf("order: %+v %+v %+v", s.Resources[0], s.Resources[1], s.Resources[2])
+	}
+	if page := s.Resources[1]; page.ContentHash != "bbb" || page.Campaign != "spring" || page.Command != "upload" {
+		t.Errorf("page: %+v", page)
+	}
+
+	// 重复记录时保留原有活动和创建时间
+	recordResources(newResource(resourceSite, "apply", 1, 0, "a2", ""))
+	if s, err = loadState(stateFilePath); err != nil {
+		t.Fatal(err)
+	}
+	if site := s.Resources[0]; len(s.Resources) != 3 || site.Name != "a2" || site.Campaign != "spring" || site.Command != "create-site" {
+		t.Errorf("upsert: %+v", site)
+	}
+
+	spring := filterResources(s.Resources, "spring", "")
+	if len(spring) != 2 {
+		t.Errorf("campaign filter: %+v", spring)
+	}
+	if ids := resourceIds(s.Resources, ""); ids != "1,2" {
+		t.Errorf("site ids = %q", ids)
+	}
+	if ids := resourceIds(filterResources(s.Resources, "", resourcePage), resourcePage); ids != "11" {
+		t.Errorf("page ids = %q", ids)
+	}
+
+	if _, err := os.Stat(stateFilePath + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind: %v", err)
+	}
+}
+
+func TestLockState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	unlock, err := lockState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 残留的锁被删除
+	stale := time.Now().Add(-2 * stateLockStale)
+	if err := os.Chtimes(path+".lock", stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	unlock2, err := lockState(path)
+	if err != nil {
+		t.Fatalf("stale lock: %v", err)
+	}
+	unlock2()
+	unlock()
+
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind: %v", err)
+	}
+}
+
+func TestLoadStateVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "resources": []}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadState(path); err == nil {
+		t.Error("expected error for unsupported version")
+	}
+}
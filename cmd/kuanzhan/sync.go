@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"pkg.blksails.net/kuanzhan"
+)
+
+var (
+	syncFull        bool          // 重新获取所有站点
+	syncMaxAge      time.Duration // 超过该时间的记录重新获取
+	syncConcurrency int           // 每个账号并发获取站点信息的数量
+)
+
+// syncResult 单个账号的同步结果
+type syncResult struct {
+	Account   string `json:"account" yaml:"account"`
+	Sites     int    `json:"sites" yaml:"sites"`
+	Refreshed int    `json:"refreshed" yaml:"refreshed"`
+	Removed   int    `json:"removed" yaml:"removed"`
+	Failed    int    `json:"failed" yaml:"failed"`
+	SyncedAt  string `json:"syncedAt,omitempty" yaml:"syncedAt,omitempty"`
+	Error     string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+var syncColumns = []outputColumn{
+	{"账号", "account"},
+	{"站点数", "sites"},
+	{"已更新", "refreshed"},
+	{"已删除", "removed"},
+	{"失败", "failed"},
+	{"同步时间", "syncedAt"},
+	{"错误", "error"},
+}
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "同步本地站点清单",
+	Long: `将站点和页面信息同步到本地清单（默认 ~/.kuanzhan/inventory.db），读命令使用 --cached 离线查询。
+默认只重新获取新增站点、上次获取失败的站点和超过 --max-age 的站点，并删除账号中已不存在的站点；--full 重新获取所有站点。
+每次同步后记录账号的快照，kuanzhan diff 使用快照报告变化。`,
+	Run: func(cmd *cobra.Command, args []string) {
+		inv, err := openInventory(inventoryPath())
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer inv.Close()
+
+		set := newClientSet()
+		accounts := set.Accounts()
+		results := make([]syncResult, len(accounts))
+		set.ForEach(context.Background(), func(ctx context.Context, a *kuanzhan.Account) error {
+			results[slices.Index(accounts, a)] = syncAccount(ctx, inv, a, siteIds, syncFull, syncMaxAge, syncConcurrency)
+			return nil
+		})
+
+		if err := renderOutput(os.Stdout, syncColumns, results); err != nil {
+			log.Fatal(err)
+		}
+		for _, r := range results {
+			if r.Error != "" || r.Failed > 0 {
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+func init() {
+	syncCmd.Flags().BoolVar(&syncFull, "full", false, "重新获取所有站点")
+	syncCmd.Flags().DurationVar(&syncMaxAge, "max-age", 24*time.Hour, "重新获取超过该时间的站点记录")
+	syncCmd.Flags().IntVar(&syncConcurrency, "concurrency", 10, "每个账号并发获取站点信息的数量")
+	syncCmd.Flags().IntSliceVarP(&siteIds, "site-ids", "i", []int{}, "只重新获取指定站点")
+	rootCmd.AddCommand(syncCmd)
+}
+
+// syncAccount 同步单个账号的站点
+// 指定 only 时只重新获取这些站点且不删除其他站点；获取失败时保留原有记录
+func syncAccount(ctx context.Context, inv *inventory, a *kuanzhan.Account, only []int, full bool, maxAge time.Duration, concurrency int) syncResult {
+	result := syncResult{Account: a.Name}
+	resp, err := a.Client.GetSiteIds()
+	if err != nil {
+		result.Error = "GetSiteIds: " + err.Error()
+		return result
+	}
+	result.Sites = len(resp.Data.SiteIds)
+
+	existing, err := inv.Sites(a.Name)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	known := make(map[int]*siteRecord, len(existing))
+	for _, rec := range existing {
+		known[rec.SiteID] = rec
+	}
+
+	var stale []int
+	for _, siteId := range resp.Data.SiteIds {
+		rec := known[siteId]
+		switch {
+		case len(only) > 0:
+			if slices.Contains(only, siteId) {
+				stale = append(stale, siteId)
+			}
+		case full, rec == nil, rec.Error != "", time.Since(rec.FetchedAt) > maxAge:
+			stale = append(stale, siteId)
+		}
+	}
+
+	if len(only) == 0 {
+		var removed []int
+		for _, rec := range existing {
+			if !slices.Contains(resp.Data.SiteIds, rec.SiteID) {
+				removed = append(removed, rec.SiteID)
+			}
+		}
+		if err := inv.DeleteSites(a.Name, removed...); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.Removed = len(removed)
+	}
+
+	var fetched []*siteRecord
+	for _, rec := range fetchSiteRecords(ctx, a.Client, a.Name, stale, concurrency, true) {
+		if rec.Error != "" {
+			result.Failed++
+			log.Printf("%s: site %d: %s", a.Name, rec.SiteID, rec.Error)
+			if known[rec.SiteID] != nil {
+				continue
+			}
+		} else {
+			result.Refreshed++
+		}
+		fetched = append(fetched, rec)
+	}
+	if err := inv.PutSites(fetched...); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	now := time.Now()
+	records, err := inv.Sites(a.Name)
+	if err == nil {
+		err = inv.PutSnapshot(a.Name, now, records)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if err := inv.SetSynced(a.Name, now); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.SyncedAt = now.Format(time.DateTime)
+	return result
+}
+
+// inventoryAccounts --cached 时查询的账号名称，不需要凭证
+func inventoryAccounts() []string {
+	if !multiProfile() {
+		return []string{profile}
+	}
+	if names := profileNamesArg(); names != nil {
+		return names
+	}
+	names, err := kuanzhan.ProfileNames(viper.ConfigFileUsed())
+	if err != nil {
+		log.Fatal(err)
+	}
+	return names
+}
+
+// cachedAccountRows 从本地清单读取账号的站点记录
+func cachedAccountRows(inv *inventory, account string) ([]siteRow, error) {
+	records, err := inv.Sites(account)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		synced, err := inv.Synced(account)
+		if err != nil {
+			return nil, err
+		}
+		if synced.IsZero() {
+			log.Printf("profile %s has never been synced, run kuanzhan sync", account)
+		}
+	}
+
+	rows := []siteRow{}
+	for _, rec := range records {
+		rows = append(rows, rec.rows(onlySite)...)
+	}
+	return rows, nil
+}
+
+// cachedSites 从本地清单读取当前 profile 的站点记录
+func cachedSites(siteIds []int) []*siteRecord {
+	inv, err := openInventory(inventoryPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer inv.Close()
+
+	records := make([]*siteRecord, 0, len(siteIds))
+	for _, siteId := range siteIds {
+		rec, err := inv.Site(profile, siteId)
+		if err != nil {
+			log.Fatal(err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
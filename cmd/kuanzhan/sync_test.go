@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pkg.blksails.net/kuanzhan"
+)
+
+func TestSyncAccount(t *testing.T) {
+	inv, err := openInventory(filepath.Join(t.TempDir(), "inventory.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inv.Close()
+
+	fake := &fakeKuanzhan{sites: map[int]*fakeSite{
+		1: {Name: "a", Domain: "a.kuaizhan.com", Pages: map[int]string{10: "首页"}},
+		2: {Name: "b", Domain: "b.kuaizhan.com"},
+	}}
+	account := &kuanzhan.Account{Name: "prod", Client: newTestServer(t, fake.ServeHTTP)}
+	ctx := context.Background()
+
+	result := syncAccount(ctx, inv, account, nil, false, time.Hour, 2)
+	if result.Error != "" || result.Sites != 2 || result.Refreshed != 2 {
+		t.Fatalf("first sync: %+v", result)
+	}
+
+	// 记录未过期时不重新获取
+	result = syncAccount(ctx, inv, account, nil, false, time.Hour, 2)
+	if result.Refreshed != 0 {
+		t.Errorf("expected no refresh: %+v", result)
+	}
+
+	// 删除的站点从清单中移除，获取失败时保留原有记录
+	delete(fake.sites, 2)
+	fake.sites[1].InfoErr = "x"
+	fake.sites[3] = &fakeSite{Name: "c", Domain: "c.kuaizhan.com"}
+	result = syncAccount(ctx, inv, account, nil, true, time.Hour, 2)
+	if result.Removed != 1 || result.Refreshed != 1 || result.Failed != 1 {
+		t.Errorf("full sync: %+v", result)
+	}
+
+	records, err := inv.Sites("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].SiteID != 1 || records[1].SiteID != 3 {
+		t.Fatalf("records: %+v", records)
+	}
+	if records[0].SiteName != "a" || records[0].Error != "" || len(records[0].Pages) != 1 {
+		t.Errorf("site 1 should keep previous record: %+v", records[0])
+	}
+
+	synced, err := inv.Synced("prod")
+	if err != nil || synced.IsZero() {
+		t.Errorf("synced = %v, %v", synced, err)
+	}
+
+	rows, err := cachedAccountRows(inv, "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].PageID != 10 || rows[0].FetchedAt == "" {
+		t.Errorf("cached rows: %+v", rows)
+	}
+
+	if _, err := inv.Site("prod", 2); err == nil {
+		t.Error("expected removed site to be missing")
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells 逐行比较的最大规模，超过时把不同的部分整体作为删除和添加
+const maxDiffCells = 4 << 20
+
+// diffOp 编辑脚本中的一行：' ' 相同，'-' 删除，'+' 添加
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff 按行比较 a 和 b，返回 unified 格式的差异，内容相同时返回空字符串
+func unifiedDiff(oldName, newName string, a, b []byte, context int) string {
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	// oldNo[k]、newNo[k] 为第 k 个操作之前的行数
+	oldNo := make([]int, len(ops)+1)
+	newNo := make([]int, len(ops)+1)
+	var changes []int
+	for k, op := range ops {
+		oldNo[k+1], newNo[k+1] = oldNo[k], newNo[k]
+		if op.kind != '+' {
+			oldNo[k+1]++
+		}
+		if op.kind != '-' {
+			newNo[k+1]++
+		}
+		if op.kind != ' ' {
+			changes = append(changes, k)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for i := 0; i < len(changes); {
+		// 相邻的变化间隔不超过 2*context 行时合并为一个 hunk
+		j := i
+		for j+1 < len(changes) && changes[j+1]-changes[j] <= 2*context {
+			j++
+		}
+		start := max(changes[i]-context, 0)
+		end := min(changes[j]+context+1, len(ops))
+
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(oldNo[start], oldNo[end]), hunkRange(newNo[start], newNo[end]))
+		for _, op := range ops[start:end] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			sb.WriteByte('\n')
+		}
+		i = j + 1
+	}
+	return sb.String()
+}
+
+// hunkRange hunk 头部的起始行和行数，没有行时起始行为前一行
+func hunkRange(from, to int) string {
+	if to == from {
+		return fmt.Sprintf("%d,0", from)
+	}
+	return fmt.Sprintf("%d,%d", from+1, to-from)
+}
+
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+// diffLines 最长公共子序列生成的编辑脚本，先去掉相同的开头和结尾
+func diffLines(a, b []string) []diffOp {
+	var prefix, suffix int
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	am, bm := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(am), len(bm)
+	if n*m > maxDiffCells {
+		for _, line := range am {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range bm {
+			ops = append(ops, diffOp{'+', line})
+		}
+	} else {
+		// lcs[i*(m+1)+j] 为 am[i:] 和 bm[j:] 的最长公共子序列长度
+		lcs := make([]int32, (n+1)*(m+1))
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if am[i] == bm[j] {
+					lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+				} else {
+					lcs[i*(m+1)+j] = max(lcs[(i+1)*(m+1)+j], lcs[i*(m+1)+j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < n && j < m {
+			switch {
+			case am[i] == bm[j]:
+				ops = append(ops, diffOp{' ', am[i]})
+				i++
+				j++
+			case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+				ops = append(ops, diffOp{'-', am[i]})
+				i++
+			default:
+				ops = append(ops, diffOp{'+', bm[j]})
+				j++
+			}
+		}
+		for ; i < n; i++ {
+			ops = append(ops, diffOp{'-', am[i]})
+		}
+		for ; j < m; j++ {
+			ops = append(ops, diffOp{'+', bm[j]})
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := []byte("<html>\n<head>\n<title>旧标题</title>\n</head>\n<body>\n<p>1</p>\n<p>2</p>\n<p>3</p>\n<p>4</p>\n<p>5</p>\n<p>6</p>\n<p>7</p>\n<p>8</p>\n<p>9</p>\n</body>\n</html>\n")
+	b := []byte("<html>\n<head>\n<title>新标题</title>\n</head>\n<body>\n<p>1</p>\n<p>2</p>\n<p>3</p>\n<p>4</p>\n<p>5</p>\n<p>6</p>\n<p>7</p>\n<p>8</p>\n<p>9</p>\n<p>10</p>\n</body>\n</html>\n")
+
+	want := `--- old
++++ new
+@@ -1,6 +1,6 @@
+ <html>
+ <head>
+-<title>旧标题</title>
++<title>新标题</title>
+ </head>
+ <body>
+ <p>1</p>
+@@ -12,5 +12,6 @@
+ <p>7</p>
+ <p>8</p>
+ <p>9</p>
++<p>10</p>
+ </body>
+ </html>
+`
+	if got := unifiedDiff("old", "new", a, b, 3); got != want {
+		t.Errorf("diff:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := unifiedDiff("old", "new", a, a, 3); got != "" {
+		t.Errorf("identical content: %q", got)
+	}
+
+	got := unifiedDiff("old", "new", nil, []byte("x\n"), 3)
+	if !strings.Contains(got, "@@ -0,0 +1,1 @@\n+x\n") {
+		t.Errorf("new file: %q", got)
+	}
+}
+
+func TestChangedPages(t *testing.T) {
+	content := []byte("new")
+	contents := map[int][]byte{1: content, 2: content, 3: content}
+	hashes := map[int]string{1: contentHash(content), 2: contentHash([]byte("old"))}
+	if got := changedPages([]int{1, 2, 3}, contents, hashes); len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Errorf("changedPages = %v", got)
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"pkg.blksails.net/kuanzhan"
+)
+
+var (
+	uploadWatch   bool          // upload 监视本地文件，变化时重新上传
+	watchDebounce time.Duration // 最后一次变化后等待的时间
+	watchExtra    []string      // 额外监视的文件或目录
+)
+
+func init() {
+	pflags := uploadSiteCmd.PersistentFlags()
+	pflags.BoolVar(&uploadWatch, "watch", false, "监视 --local-path，变化时重新上传到 --page-ids 并发布，Ctrl+C 退出")
+	pflags.DurationVar(&watchDebounce, "debounce", 300*time.Millisecond, "--watch 时最后一次变化后等待的时间")
+	pflags.StringArrayVar(&watchExtra, "watch-path", nil, "--watch 时额外监视的文件或目录，可以指定多次")
+}
+
+// watchUploader --watch 时将本地文件上传到指定页面
+type watchUploader struct {
+	client    *kuanzhan.Client
+	path      string
+	siteIds   []int
+	pageIds   []int
+	pageSites map[int]int
+	domains   map[int]string
+	hashes    map[int]string // 每个页面上次上传内容的哈希
+	out       io.Writer
+
+	charset string
+	render  string
+	values  string
+	set     []string
+	name    string
+}
+
+// newWatchUploader 确定每个页面所属的站点
+func newWatchUploader(client *kuanzhan.Client, path string, siteIds, pageIds []int) (*watchUploader, error) {
+	inv, err := openInventory(inventoryPath())
+	if err != nil {
+		return nil, err
+	}
+	pageSites := resolvePageSites(client, inv, siteIds, pageIds, nil)
+	inv.Close()
+	for _, pageId := range pageIds {
+		if pageSites[pageId] == 0 {
+			return nil, fmt.Errorf("page %d does not belong to any of sites %v", pageId, siteIds)
+		}
+	}
+	return &watchUploader{
+		client:    client,
+		path:      path,
+		siteIds:   siteIds,
+		pageIds:   pageIds,
+		pageSites: pageSites,
+		domains:   make(map[int]string),
+		hashes:    make(map[int]string),
+		out:       os.Stdout,
+	}, nil
+}
+
+// push 读取本地文件，修改并发布内容有变化的页面，输出页面地址
+// 单个页面失败时继续上传其他页面，返回所有错误
+func (u *watchUploader) push() error {
+	content, err := readLocalPage(u.path, u.charset)
+	if err != nil {
+		return err
+	}
+	var tmpl *pageTemplate
+	if u.render != "" {
+		if tmpl, err = newPageTemplate(u.render, content, u.values, u.set); err != nil {
+			return err
+		}
+	}
+	contents, _, err := pageContents(u.client, tmpl, content, u.siteIds, u.pageIds, u.pageSites, u.name)
+	if err != nil {
+		return err
+	}
+
+	source, _ := filepath.Abs(u.path)
+	var errs []error
+	uploaded := 0
+	for _, pageId := range u.pageIds {
+		siteId, hash := u.pageSites[pageId], contentHash(contents[pageId])
+		if u.hashes[pageId] == hash {
+			continue
+		}
+		if _, err := u.client.ModifyPageJs(siteId, strconv.Itoa(pageId), string(contents[pageId]), false); err != nil {
+			errs = append(errs, fmt.Errorf("page %d: ModifyPageJs: %w", pageId, err))
+			continue
+		}
+		if _, err := u.client.PublishPage(siteId, pageId); err != nil {
+			errs = append(errs, fmt.Errorf("page %d: PublishPage: %w", pageId, err))
+			continue
+		}
+		u.hashes[pageId] = hash
+		uploaded++
+
+		link := strconv.Itoa(pageId)
+		if domain, err := u.domain(siteId); err == nil {
+			link = pageLink(domain, pageId)
+		}
+		fmt.Fprintf(u.out, "%s published %s\n", time.Now().Format(time.TimeOnly), link)
+
+		if err := archiveUpload(u.client, contents[pageId], source, "", u.siteIds, []int{pageId}, u.pageSites); err != nil {
+			log.Println("warning: archive upload:", err)
+		}
+		recordContent([]int{pageId}, hash)
+	}
+	if uploaded == 0 && len(errs) == 0 {
+		fmt.Fprintf(u.out, "%s no changes\n", time.Now().Format(time.TimeOnly))
+	}
+	return errors.Join(errs...)
+}
+
+// domain 站点域名，第一次使用时获取
+func (u *watchUploader) domain(siteId int) (string, error) {
+	if domain, ok := u.domains[siteId]; ok {
+		return domain, nil
+	}
+	resp, err := u.client.GetSiteInfo(siteId)
+	if err != nil {
+		return "", err
+	}
+	u.domains[siteId] = resp.Data.SiteDomain
+	return resp.Data.SiteDomain, nil
+}
+
+// watchUpload upload --watch：上传一次，然后在文件变化时重新上传，直到 Ctrl+C
+func watchUpload() {
+	if localPath == "" || len(pageIds) == 0 {
+		log.Fatal("--watch requires --local-path and --page-ids")
+	}
+	u, err := newWatchUploader(newClient(), localPath, siteIds, pageIds)
+	if err != nil {
+		log.Fatal(err)
+	}
+	u.charset, u.render, u.values, u.set, u.name = importOpts.Charset, renderMode, valuesFile, setValues, pageName
+
+	paths := append([]string{localPath}, watchExtra...)
+	if valuesFile != "" {
+		paths = append(paths, valuesFile)
+	}
+	w, err := newPathWatcher(paths)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer w.Close()
+
+	if err := u.push(); err != nil {
+		log.Println("error:", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Printf("watching %s, press Ctrl+C to stop", strings.Join(paths, ", "))
+	w.run(ctx, watchDebounce, func() {
+		if err := u.push(); err != nil {
+			log.Println("error:", err)
+		}
+	})
+}
+
+// pathWatcher 监视文件和目录的变化
+type pathWatcher struct {
+	*fsnotify.Watcher
+	files map[string]bool // 监视的文件
+	dirs  []string        // 监视的目录，包括子目录
+}
+
+// newPathWatcher 监视 paths 中的文件和目录
+// 编辑器保存时可能先删除或重命名文件，因此文件通过其所在目录监视
+func newPathWatcher(paths []string) (*pathWatcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &pathWatcher{Watcher: fw, files: make(map[string]bool)}
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			w.Close()
+			return nil, err
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			w.Close()
+			return nil, err
+		}
+		if info.IsDir() {
+			w.dirs = append(w.dirs, abs)
+			err = w.addDir(abs)
+		} else {
+			w.files[abs] = true
+			err = w.Add(filepath.Dir(abs))
+		}
+		if err != nil {
+			w.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// addDir 监视目录及其子目录，跳过 . 开头的目录
+func (w *pathWatcher) addDir(dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		return w.Add(p)
+	})
+}
+
+// watched 是否为监视的文件或监视目录中的文件
+func (w *pathWatcher) watched(name string) bool {
+	if w.files[name] {
+		return true
+	}
+	for _, dir := range w.dirs {
+		if strings.HasPrefix(name, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// run 在监视的文件变化且 debounce 内没有新的变化后调用 fn，直到 ctx 结束
+func (w *pathWatcher) run(ctx context.Context, debounce time.Duration, fn func()) {
+	var timer <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if ev.Op == fsnotify.Chmod || !w.watched(ev.Name) {
+				continue
+			}
+			if ev.Has(fsnotify.Create) {
+				// 监视目录中新建的子目录
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() && !w.files[ev.Name] {
+					if err := w.addDir(ev.Name); err != nil {
+						log.Println("watch:", err)
+					}
+				}
+			}
+			timer = time.After(debounce)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			log.Println("watch:", err)
+		case <-timer:
+			timer = nil
+			fn()
+		}
+	}
+}
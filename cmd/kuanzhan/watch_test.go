@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchUploader(t *testing.T) {
+	oldState, oldInventory, oldProfile := stateFilePath, inventoryFile, profile
+	t.Cleanup(func() { stateFilePath, inventoryFile, profile = oldState, oldInventory, oldProfile })
+	dir := t.TempDir()
+	stateFilePath = filepath.Join(dir, "state.json")
+	inventoryFile = filepath.Join(dir, "inventory.db")
+	profile = "default"
+
+	fake := &fakeKuanzhan{sites: map[int]*fakeSite{
+		1: {Name: "a", Domain: "a.kuaizhan.com", Pages: map[int]string{10: "首页", 11: "活动页"}},
+	}}
+	client := newTestServer(t, fake.ServeHTTP)
+
+	page := filepath.Join(dir, "index.html")
+	if err := os.WriteFile(page, []byte("<p>v1</p>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	u, err := newWatchUploader(client, page, []int{1}, []int{10, 11})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	u.out = &out
+
+	if err := u.push(); err != nil {
+		t.Fatal(err)
+	}
+	site := fake.sites[1]
+	if site.Contents[10] != "<p>v1</p>" || site.Published[11] != 1 {
+		t.Errorf("first push: %+v", site)
+	}
+	if !strings.Contains(out.String(), "published https://a.kuaizhan.com/10") {
+		t.Errorf("output: %s", out.String())
+	}
+
+	// 内容没有变化时不上传
+	out.Reset()
+	if err := u.push(); err != nil || !strings.Contains(out.String(), "no changes") || site.Published[10] != 1 {
+		t.Errorf("unchanged push: %v, %s", err, out.String())
+	}
+
+	// 单个页面失败时继续上传其他页面
+	delete(site.Pages, 11)
+	os.WriteFile(page, []byte("<p>v2</p>"), 0644)
+	err = u.push()
+	if err == nil || !strings.Contains(err.Error(), "page 11") {
+		t.Errorf("expected error for page 11, got %v", err)
+	}
+	if site.Contents[10] != "<p>v2</p>" || site.Published[10] != 2 {
+		t.Errorf("page 10 not uploaded: %+v", site)
+	}
+
+	if _, err := newWatchUploader(client, page, []int{1, 2}, []int{99}); err == nil {
+		t.Error("expected error for page outside the sites")
+	}
+}
+
+func TestPathWatcher(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "index.html")
+	assets := filepath.Join(dir, "assets")
+	for _, d := range []string{assets, filepath.Join(dir, "other")} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(page, []byte("v0"), 0644)
+
+	w, err := newPathWatcher([]string{page, assets})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	calls := make(chan struct{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.run(ctx, 50*time.Millisecond, func() { calls <- struct{}{} })
+
+	expect := func(what string, want int) {
+		t.Helper()
+		got := 0
+		timeout := time.After(500 * time.Millisecond)
+		for {
+			select {
+			case <-calls:
+				got++
+				continue
+			case <-timeout:
+			}
+			break
+		}
+		if got != want {
+			t.Errorf("%s: %d calls, want %d", what, got, want)
+		}
+	}
+
+	// 连续的变化只触发一次
+	for i := range 3 {
+		os.WriteFile(page, []byte("v"+string(rune('1'+i))), 0644)
+	}
+	expect("write page", 1)
+
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0644)
+	expect("unwatched file", 0)
+
+	// 替换文件（编辑器保存）
+	tmp := filepath.Join(dir, "index.html.swp")
+	os.WriteFile(tmp, []byte("v4"), 0644)
+	os.Rename(tmp, page)
+	expect("rename over page", 1)
+
+	os.Mkdir(filepath.Join(assets, "img"), 0755)
+	expect("new directory", 1)
+	os.WriteFile(filepath.Join(assets, "img", "a.png"), []byte("png"), 0644)
+	expect("file in new directory", 1)
+}
@@ -0,0 +1,310 @@
+package kuanzhan
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 凭证相关的环境变量
+const (
+	EnvAppKey    = "KUANZHAN_APP_KEY"
+	EnvAppSecret = "KUANZHAN_APP_SECRET"
+
+	// 兼容旧的测试环境变量命名
+	legacyEnvAppKey    = "KUAIZHAN_APP_KEY"
+	legacyEnvAppSecret = "KUAIZHAN_APP_SECRET"
+)
+
+// ErrNoCredentials 凭证链中没有找到完整的 app_key/app_secret
+var ErrNoCredentials = errors.New("kuanzhan: no credentials found")
+
+// Credentials 快站API凭证，记录每个字段的来源
+type Credentials struct {
+	AppKey          string
+	AppSecret       string
+	AppKeySource    string
+	AppSecretSource string
+}
+
+// Complete 是否同时包含 app_key 和 app_secret
+func (c Credentials) Complete() bool {
+	return c.AppKey != "" && c.AppSecret != ""
+}
+
+// merge 用 o 补全 c 中缺失的字段
+func (c *Credentials) merge(o Credentials) {
+	if c.AppKey == "" && o.AppKey != "" {
+		c.AppKey = o.AppKey
+		c.AppKeySource = o.AppKeySource
+	}
+	if c.AppSecret == "" && o.AppSecret != "" {
+		c.AppSecret = o.AppSecret
+		c.AppSecretSource = o.AppSecretSource
+	}
+}
+
+// CredentialProvider 凭证提供者
+// Retrieve 可以只返回部分字段，缺失的字段由凭证链中后续的提供者补全
+type CredentialProvider interface {
+	Retrieve() (Credentials, error)
+}
+
+// StaticProvider 显式指定的凭证
+type StaticProvider struct {
+	AppKey    string
+	AppSecret string
+}
+
+// Retrieve
+func (p *StaticProvider) Retrieve() (Credentials, error) {
+	var creds Credentials
+	if p.AppKey != "" {
+		creds.AppKey, creds.AppKeySource = p.AppKey, "explicit"
+	}
+	if p.AppSecret != "" {
+		creds.AppSecret, creds.AppSecretSource = p.AppSecret, "explicit"
+	}
+	return creds, nil
+}
+
+// EnvProvider 从环境变量 KUANZHAN_APP_KEY/KUANZHAN_APP_SECRET 读取凭证
+type EnvProvider struct{}
+
+// Retrieve
+func (p *EnvProvider) Retrieve() (Credentials, error) {
+	var creds Credentials
+	for _, name := range []string{EnvAppKey, legacyEnvAppKey} {
+		if v := os.Getenv(name); v != "" {
+			creds.AppKey, creds.AppKeySource = v, "env:"+name
+			break
+		}
+	}
+	for _, name := range []string{EnvAppSecret, legacyEnvAppSecret} {
+		if v := os.Getenv(name); v != "" {
+			creds.AppSecret, creds.AppSecretSource = v, "env:"+name
+			break
+		}
+	}
+	return creds, nil
+}
+
+// ProfileConfig 配置文件中单个 profile 的内容
+type ProfileConfig struct {
+	AppKey            string  `yaml:"app_key"`
+	AppSecret         string  `yaml:"app_secret"`
+	CredentialProcess string  `yaml:"credential_process"`
+	RateLimit         float64 `yaml:"rate_limit"` // 每秒最多请求数，0 表示不限制
+}
+
+type fileConfig struct {
+	ProfileConfig `yaml:",inline"`
+	Profiles      map[string]ProfileConfig `yaml:"profiles"`
+}
+
+// FileProvider 从 kuanzhan.yaml 的 profile 中读取凭证
+// profile 为 default 时，profiles.default 中缺失的字段使用顶级的 app_key/app_secret。
+// 如果 profile 配置了 credential_process，会执行该命令补全缺失的字段。
+// enc: 前缀的加密值使用 Passphrase 透明解密，未设置时使用 DefaultPassphrase。
+type FileProvider struct {
+	Path       string
+	Profile    string
+	Passphrase PassphraseFunc
+}
+
+// Load 读取 profile 配置
+func (p *FileProvider) Load() (ProfileConfig, error) {
+	var config ProfileConfig
+	if p.Path == "" {
+		return config, nil
+	}
+
+	b, err := os.ReadFile(p.Path)
+	if err != nil {
+		return config, err
+	}
+
+	var fc fileConfig
+	if err := yaml.Unmarshal(b, &fc); err != nil {
+		return config, fmt.Errorf("parse %s: %w", p.Path, err)
+	}
+
+	name := p.profileName()
+	if pc, ok := fc.Profiles[name]; ok {
+		config = pc
+	} else if name != "default" {
+		return config, fmt.Errorf("profile %q not found in %s", name, p.Path)
+	}
+
+	if name == "default" {
+		if config.AppKey == "" {
+			config.AppKey = fc.AppKey
+		}
+		if config.AppSecret == "" {
+			config.AppSecret = fc.AppSecret
+		}
+		if config.CredentialProcess == "" {
+			config.CredentialProcess = fc.CredentialProcess
+		}
+	}
+
+	if config.AppKey, err = p.decrypt(config.AppKey); err != nil {
+		return config, err
+	}
+	if config.AppSecret, err = p.decrypt(config.AppSecret); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+func (p *FileProvider) decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	passphrase := p.Passphrase
+	if passphrase == nil {
+		passphrase = DefaultPassphrase
+	}
+	key, err := passphrase()
+	if err != nil {
+		return "", err
+	}
+	return DecryptSecret(value, key)
+}
+
+func (p *FileProvider) profileName() string {
+	if p.Profile == "" {
+		return "default"
+	}
+	return p.Profile
+}
+
+// Retrieve
+func (p *FileProvider) Retrieve() (Credentials, error) {
+	var creds Credentials
+	config, err := p.Load()
+	if err != nil {
+		return creds, err
+	}
+
+	source := fmt.Sprintf("file:%s#%s", p.Path, p.profileName())
+	if config.AppKey != "" {
+		creds.AppKey, creds.AppKeySource = config.AppKey, source
+	}
+	if config.AppSecret != "" {
+		creds.AppSecret, creds.AppSecretSource = config.AppSecret, source
+	}
+
+	if !creds.Complete() && config.CredentialProcess != "" {
+		processCreds, err := (&ProcessProvider{Command: config.CredentialProcess}).Retrieve()
+		if err != nil {
+			return creds, err
+		}
+		creds.merge(processCreds)
+	}
+	return creds, nil
+}
+
+// ProcessProvider 执行外部命令获取凭证
+// 命令需要在标准输出打印 {"app_key": "...", "app_secret": "..."}
+type ProcessProvider struct {
+	Command string
+}
+
+// Retrieve
+func (p *ProcessProvider) Retrieve() (Credentials, error) {
+	var creds Credentials
+	if p.Command == "" {
+		return creds, nil
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", p.Command)
+	} else {
+		cmd = exec.Command("sh", "-c", p.Command)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return creds, fmt.Errorf("credential_process %q: %w: %s", p.Command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var out struct {
+		AppKey    string `json:"app_key"`
+		AppSecret string `json:"app_secret"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return creds, fmt.Errorf("credential_process %q: invalid output: %w", p.Command, err)
+	}
+
+	source := "process:" + p.Command
+	if out.AppKey != "" {
+		creds.AppKey, creds.AppKeySource = out.AppKey, source
+	}
+	if out.AppSecret != "" {
+		creds.AppSecret, creds.AppSecretSource = out.AppSecret, source
+	}
+	return creds, nil
+}
+
+// ChainProvider 按顺序查询多个提供者，逐个字段补全凭证
+type ChainProvider struct {
+	Providers []CredentialProvider
+}
+
+// NewChainProvider creates a new chain provider
+func NewChainProvider(providers ...CredentialProvider) *ChainProvider {
+	return &ChainProvider{Providers: providers}
+}
+
+// Retrieve
+func (p *ChainProvider) Retrieve() (Credentials, error) {
+	var creds Credentials
+	for _, provider := range p.Providers {
+		c, err := provider.Retrieve()
+		if err != nil {
+			return creds, err
+		}
+		creds.merge(c)
+		if creds.Complete() {
+			return creds, nil
+		}
+	}
+
+	var missing []string
+	if creds.AppKey == "" {
+		missing = append(missing, "app_key")
+	}
+	if creds.AppSecret == "" {
+		missing = append(missing, "app_secret")
+	}
+	return creds, fmt.Errorf("%w: missing %s", ErrNoCredentials, strings.Join(missing, ", "))
+}
+
+// DefaultProvider 默认凭证链：显式指定 -> 环境变量 -> 配置文件 profile -> credential_process
+func DefaultProvider(appKey, appSecret, path, profile string) *ChainProvider {
+	return NewChainProvider(
+		&StaticProvider{AppKey: appKey, AppSecret: appSecret},
+		&EnvProvider{},
+		&FileProvider{Path: path, Profile: profile},
+	)
+}
+
+// NewClientWithProvider 使用凭证提供者创建客户端
+func NewClientWithProvider(provider CredentialProvider) (*Client, error) {
+	creds, err := provider.Retrieve()
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(creds.AppKey, creds.AppSecret), nil
+}
@@ -0,0 +1,108 @@
+package kuanzhan
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+var testConfig = `app_key: top_key
+app_secret: top_secret
+profiles:
+  default:
+    app_key: default_key
+  production:
+    app_key: prod_key
+    app_secret: prod_secret
+  process:
+    credential_process: echo '{"app_key":"proc_key","app_secret":"proc_secret"}'
+`
+
+func writeTestConfig(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "kuanzhan.yaml")
+	if err := os.WriteFile(path, []byte(testConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFileProvider_Retrieve(t *testing.T) {
+	path := writeTestConfig(t)
+
+	tests := []struct {
+		profile   string
+		appKey    string
+		appSecret string
+	}{
+		{"", "default_key", "top_secret"},
+		{"default", "default_key", "top_secret"},
+		{"production", "prod_key", "prod_secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			creds, err := (&FileProvider{Path: path, Profile: tt.profile}).Retrieve()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if creds.AppKey != tt.appKey || creds.AppSecret != tt.appSecret {
+				t.Errorf("got %s/%s, want %s/%s", creds.AppKey, creds.AppSecret, tt.appKey, tt.appSecret)
+			}
+		})
+	}
+
+	if _, err := (&FileProvider{Path: path, Profile: "missing"}).Retrieve(); err == nil {
+		t.Error("expected error for missing profile")
+	}
+}
+
+func TestFileProvider_CredentialProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("credential_process test uses sh")
+	}
+	path := writeTestConfig(t)
+	creds, err := (&FileProvider{Path: path, Profile: "process"}).Retrieve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.AppKey != "proc_key" || creds.AppSecret != "proc_secret" {
+		t.Errorf("got %s/%s", creds.AppKey, creds.AppSecret)
+	}
+	if creds.AppKeySource[:8] != "process:" {
+		t.Errorf("unexpected source %s", creds.AppKeySource)
+	}
+}
+
+func TestChainProvider_Retrieve(t *testing.T) {
+	path := writeTestConfig(t)
+	t.Setenv(EnvAppKey, "env_key")
+	t.Setenv(EnvAppSecret, "")
+	t.Setenv(legacyEnvAppSecret, "")
+
+	creds, err := DefaultProvider("", "", path, "production").Retrieve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.AppKey != "env_key" || creds.AppKeySource != "env:"+EnvAppKey {
+		t.Errorf("app_key %s from %s", creds.AppKey, creds.AppKeySource)
+	}
+	if creds.AppSecret != "prod_secret" {
+		t.Errorf("app_secret %s from %s", creds.AppSecret, creds.AppSecretSource)
+	}
+
+	creds, err = DefaultProvider("flag_key", "flag_secret", path, "production").Retrieve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.AppKeySource != "explicit" || creds.AppSecretSource != "explicit" {
+		t.Errorf("expected explicit credentials, got %s/%s", creds.AppKeySource, creds.AppSecretSource)
+	}
+
+	t.Setenv(EnvAppKey, "")
+	t.Setenv(legacyEnvAppKey, "")
+	_, err = DefaultProvider("", "", "", "default").Retrieve()
+	if !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("expected ErrNoCredentials, got %v", err)
+	}
+}
@@ -4,21 +4,26 @@ go 1.23.3
 
 require (
 	github.com/fatih/structs v1.1.0
+	github.com/fsnotify/fsnotify v1.8.0
 	github.com/go-viper/mapstructure/v2 v2.3.0
 	github.com/kr/pretty v0.3.1
 	github.com/minio/selfupdate v0.6.0
 	github.com/olekukonko/tablewriter v1.0.7
 	github.com/spf13/cobra v1.9.1
 	github.com/spf13/viper v1.20.1
+	go.etcd.io/bbolt v1.4.0
+	golang.org/x/crypto v0.32.0
 	golang.org/x/mod v0.17.0
 	golang.org/x/net v0.33.0
 	golang.org/x/sync v0.16.0
+	golang.org/x/text v0.21.0
+	golang.org/x/time v0.8.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	aead.dev/minisign v0.2.0 // indirect
 	github.com/fatih/color v1.15.0 // indirect
-	github.com/fsnotify/fsnotify v1.8.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/kr/text v0.2.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
@@ -37,8 +42,5 @@ require (
 	github.com/subosito/gotenv v1.6.0 // indirect
 	go.uber.org/atomic v1.9.0 // indirect
 	go.uber.org/multierr v1.9.0 // indirect
-	golang.org/x/crypto v0.32.0 // indirect
 	golang.org/x/sys v0.29.0 // indirect
-	golang.org/x/text v0.21.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
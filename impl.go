@@ -46,6 +46,10 @@ func (m *methodImpl[R, Q]) Do(client *Client, params Q) (resp *R, err error) {
 		return
 	}
 
+	if err = client.wait(); err != nil {
+		return
+	}
+
 	signedParams := client.BuildSignedParams(pParams)
 	var form = url.Values{}
 	for k, v := range signedParams {
@@ -114,6 +118,10 @@ func (m *methodImpl[R, Q]) PostJSON(client *Client, params Q) (resp *R, err erro
 		return
 	}
 
+	if err = client.wait(); err != nil {
+		return
+	}
+
 	signedParams := client.BuildSignedParams(pParams)
 
 	var form = url.Values{}
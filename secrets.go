@@ -0,0 +1,146 @@
+package kuanzhan
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 加密口令相关的环境变量
+const (
+	EnvPassphrase = "KUANZHAN_PASSPHRASE"
+	EnvKeyFile    = "KUANZHAN_KEY_FILE"
+)
+
+// 加密值格式: enc:v1:base64(salt|nonce|ciphertext)
+// 使用 scrypt 从口令派生密钥，XChaCha20-Poly1305 加密
+const (
+	encPrefix    = "enc:"
+	encVersion   = "v1:"
+	saltSize     = 16
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = chacha20poly1305.KeySize
+)
+
+// ErrNoPassphrase 配置中存在加密值但没有可用的口令
+var ErrNoPassphrase = errors.New("kuanzhan: encrypted secret requires a passphrase (set " + EnvPassphrase + " or " + EnvKeyFile + ")")
+
+// PassphraseFunc 返回用于加解密的口令
+type PassphraseFunc func() ([]byte, error)
+
+// IsEncrypted 是否为 enc: 前缀的加密值
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encPrefix)
+}
+
+// EncryptSecret 使用口令加密，返回 enc: 前缀的字符串
+func EncryptSecret(plaintext string, passphrase []byte) (string, error) {
+	if len(passphrase) == 0 {
+		return "", ErrNoPassphrase
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 0, len(salt)+len(nonce)+len(plaintext)+aead.Overhead())
+	buf = append(buf, salt...)
+	buf = append(buf, nonce...)
+	buf = aead.Seal(buf, nonce, []byte(plaintext), nil)
+	return encPrefix + encVersion + base64.RawStdEncoding.EncodeToString(buf), nil
+}
+
+// DecryptSecret 解密 enc: 前缀的字符串，非加密值原样返回
+func DecryptSecret(value string, passphrase []byte) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	if len(passphrase) == 0 {
+		return "", ErrNoPassphrase
+	}
+
+	payload, ok := strings.CutPrefix(strings.TrimPrefix(value, encPrefix), encVersion)
+	if !ok {
+		return "", fmt.Errorf("kuanzhan: unsupported encrypted secret version")
+	}
+
+	buf, err := base64.RawStdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("kuanzhan: invalid encrypted secret: %w", err)
+	}
+	if len(buf) < saltSize+chacha20poly1305.NonceSizeX {
+		return "", fmt.Errorf("kuanzhan: invalid encrypted secret: too short")
+	}
+
+	salt, rest := buf[:saltSize], buf[saltSize:]
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+
+	nonce, ciphertext := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("kuanzhan: decrypt secret: wrong passphrase or corrupted value")
+	}
+	return string(plaintext), nil
+}
+
+func newAEAD(passphrase, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.NewX(key)
+}
+
+// DefaultPassphrase 依次从 KUANZHAN_PASSPHRASE、KUANZHAN_KEY_FILE、~/.kuanzhan/key 读取口令
+func DefaultPassphrase() ([]byte, error) {
+	if v := os.Getenv(EnvPassphrase); v != "" {
+		return []byte(v), nil
+	}
+	if path := os.Getenv(EnvKeyFile); path != "" {
+		return ReadKeyFile(path)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		path := filepath.Join(home, ".kuanzhan", "key")
+		if _, err := os.Stat(path); err == nil {
+			return ReadKeyFile(path)
+		}
+	}
+	return nil, ErrNoPassphrase
+}
+
+// ReadKeyFile 读取密钥文件，去掉首尾空白后作为口令
+func ReadKeyFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key := []byte(strings.TrimSpace(string(b)))
+	if len(key) == 0 {
+		return nil, fmt.Errorf("kuanzhan: key file %s is empty", path)
+	}
+	return key, nil
+}
@@ -0,0 +1,63 @@
+package kuanzhan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecryptSecret(t *testing.T) {
+	passphrase := []byte("correct horse battery staple")
+
+	value, err := EncryptSecret("my_app_secret", passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(value) || strings.Contains(value, "my_app_secret") {
+		t.Fatalf("unexpected encrypted value %s", value)
+	}
+
+	plaintext, err := DecryptSecret(value, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != "my_app_secret" {
+		t.Errorf("got %s", plaintext)
+	}
+
+	if _, err := DecryptSecret(value, []byte("wrong")); err == nil {
+		t.Error("expected error for wrong passphrase")
+	}
+
+	if v, err := DecryptSecret("plain", nil); err != nil || v != "plain" {
+		t.Errorf("plain value: %s, %v", v, err)
+	}
+}
+
+func TestFileProvider_EncryptedSecret(t *testing.T) {
+	passphrase := []byte("passphrase")
+	value, err := EncryptSecret("prod_secret", passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "kuanzhan.yaml")
+	config := "profiles:\n  production:\n    app_key: prod_key\n    app_secret: " + value + "\n"
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &FileProvider{
+		Path:       path,
+		Profile:    "production",
+		Passphrase: func() ([]byte, error) { return passphrase, nil },
+	}
+	creds, err := provider.Retrieve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.AppSecret != "prod_secret" {
+		t.Errorf("got %s", creds.AppSecret)
+	}
+}